@@ -0,0 +1,55 @@
+#!/bin/bash
+
+# Markdown rendering benchmark
+# Runs BenchmarkRender (cli/internal/content/renderer_test.go) on the working
+# tree and on another git revision, using the working tree's benchmark for both
+#
+# Usage: ./render.sh [base-ref] [count]
+#   base-ref  Revision to compare against (default: HEAD~1)
+#   count     Benchmark runs per revision (default: 5)
+#
+# Prints a benchstat comparison when benchstat is installed
+# (go install golang.org/x/perf/cmd/benchstat@latest)
+
+set -e
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+REPO_DIR="$(cd "${SCRIPT_DIR}/.." && pwd)"
+BASE_REF=${1:-HEAD~1}
+COUNT=${2:-5}
+
+WORKDIR=$(mktemp -d)
+cleanup() {
+    git -C "$REPO_DIR" worktree remove --force "$WORKDIR/base" 2>/dev/null || true
+    rm -rf "$WORKDIR"
+}
+trap cleanup EXIT
+
+echo "Markdown Rendering Benchmark"
+echo "============================"
+echo ""
+echo "Base: $BASE_REF"
+echo "Runs: $COUNT"
+echo ""
+
+# Check out the base and give it the same benchmark
+git -C "$REPO_DIR" worktree add --detach -q "$WORKDIR/base" "$BASE_REF"
+cp "$REPO_DIR/cli/internal/content/renderer_test.go" "$WORKDIR/base/cli/internal/content/"
+mkdir -p "$WORKDIR/base/cli/internal/content/testdata"
+cp -r "$REPO_DIR/cli/internal/content/testdata/render" "$REPO_DIR/cli/internal/content/testdata/render-golden" \
+    "$WORKDIR/base/cli/internal/content/testdata/"
+
+run_bench() {
+    (cd "$1/cli" && go test ./internal/content -run '^$' -bench '^BenchmarkRender$' -benchmem -count "$COUNT")
+}
+
+echo "Running $BASE_REF..."
+run_bench "$WORKDIR/base" | tee "$WORKDIR/base.txt"
+echo ""
+echo "Running working tree..."
+run_bench "$REPO_DIR" | tee "$WORKDIR/head.txt"
+
+if command -v benchstat > /dev/null; then
+    echo ""
+    benchstat "$WORKDIR/base.txt" "$WORKDIR/head.txt"
+fi
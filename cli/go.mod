@@ -3,6 +3,7 @@ module github.com/shivamx96/leafpress/cli
 go 1.25.5
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/alecthomas/chroma/v2 v2.21.1
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/gorilla/websocket v1.5.3
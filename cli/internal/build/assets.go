@@ -0,0 +1,169 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// immutableCacheControl is sent for fingerprinted files: a new version gets a new name
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// fingerprintName adds a content hash to a file name: style.css -> style.3fa2c1d4.css
+func fingerprintName(name string, data []byte) string {
+	sum := sha256.Sum256(data)
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+}
+
+// writeAsset writes a file that templates link to, such as style.css. With
+// fingerprinting on, it's written under its hashed name, which is recorded
+// in the asset manifest.
+func (b *Builder) writeAsset(name string, data []byte) error {
+	if b.fingerprints != nil {
+		hashed := fingerprintName(name, data)
+		b.fingerprints[name] = hashed
+		name = hashed
+	}
+	outPath := filepath.Join(b.outputDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
+
+// assetPath returns the output path of an asset, fingerprinted if it was written that way
+func (b *Builder) assetPath(name string) string {
+	if hashed, ok := b.fingerprints[name]; ok {
+		return hashed
+	}
+	return name
+}
+
+// fingerprintStatic writes a hashed copy of each static file that templates
+// link to with Site.Asset. Other static files, which only notes and user CSS
+// link to by name, aren't fingerprinted.
+func (b *Builder) fingerprintStatic() error {
+	for _, name := range b.templates.AssetNames() {
+		if !strings.HasPrefix(name, "static/") {
+			continue
+		}
+		// The site's static/ wins over the theme's, like copyStatic
+		dirs := b.overrideDirs()
+		for i := len(dirs) - 1; i >= 0; i-- {
+			data, err := os.ReadFile(filepath.Join(dirs[i], filepath.FromSlash(name)))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := b.writeAsset(name, data); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// writeAssetManifest writes asset-manifest.json, mapping original names to
+// fingerprinted ones, and for Netlify and Vercel the header file that lets
+// browsers and CDNs cache fingerprinted files forever. Rules the site already
+// has are kept. Returns warnings for header files it can't write.
+func (b *Builder) writeAssetManifest() ([]string, error) {
+	manifest, err := json.MarshalIndent(b.fingerprints, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(b.outputDir, "asset-manifest.json"), manifest, 0644); err != nil {
+		return nil, err
+	}
+
+	hashed := make([]string, 0, len(b.fingerprints))
+	for _, name := range b.fingerprints {
+		hashed = append(hashed, "/"+name)
+	}
+	sort.Strings(hashed)
+
+	switch b.cfg.Deploy.Provider {
+	case "netlify":
+		return nil, b.writeNetlifyHeaders(hashed)
+	case "vercel":
+		return b.writeVercelHeaders(hashed)
+	}
+	return nil, nil
+}
+
+// writeNetlifyHeaders writes _headers to the output, after the rules in the
+// site's own _headers file if it has one
+func (b *Builder) writeNetlifyHeaders(hashed []string) error {
+	var sb strings.Builder
+	existing, err := os.ReadFile(filepath.Join(b.rootDir, "_headers"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(existing) > 0 {
+		sb.Write(existing)
+		if !strings.HasSuffix(string(existing), "\n") {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("# Fingerprinted assets change name when their content changes\n")
+	for _, name := range hashed {
+		sb.WriteString(name + "\n  Cache-Control: " + immutableCacheControl + "\n")
+	}
+	return os.WriteFile(filepath.Join(b.outputDir, "_headers"), []byte(sb.String()), 0644)
+}
+
+// writeVercelHeaders writes vercel.json to the output, which leafpress deploy
+// uploads as the project root. With Vercel's Git integration the project root
+// is the site, whose own vercel.json isn't touched; its headers must be added
+// by hand, so there's a warning instead.
+func (b *Builder) writeVercelHeaders(hashed []string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(b.rootDir, "vercel.json")); err == nil {
+		return []string{"vercel.json exists in the site root, so Cache-Control headers for fingerprinted assets weren't written: add them there (see asset-manifest.json)"}, nil
+	}
+
+	type header struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	type route struct {
+		Source  string   `json:"source"`
+		Headers []header `json:"headers"`
+	}
+	vercel := struct {
+		Headers []route `json:"headers"`
+	}{Headers: []route{}}
+	for _, name := range hashed {
+		vercel.Headers = append(vercel.Headers, route{
+			Source:  vercelSource(name),
+			Headers: []header{{Key: "Cache-Control", Value: immutableCacheControl}},
+		})
+	}
+
+	data, err := json.MarshalIndent(vercel, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return nil, os.WriteFile(filepath.Join(b.outputDir, "vercel.json"), data, 0644)
+}
+
+// vercelSource escapes the characters that Vercel's source patterns treat specially
+func vercelSource(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`\()[]{}?+*:!`, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,50 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shivamx96/leafpress/cli/internal/config"
+)
+
+func TestScriptInlinedByDefault(t *testing.T) {
+	writeSite(t, map[string]string{"index.md": "---\ntitle: Home\n---\nHello\n"})
+	b, _ := buildSite(t, config.Default())
+
+	page := readOutput(t, b, "index.html")
+	if !strings.Contains(page, "document.addEventListener('DOMContentLoaded'") {
+		t.Error("index.html doesn't inline the site script")
+	}
+	if strings.Contains(page, "script.js") {
+		t.Error("index.html links to script.js without fingerprinting")
+	}
+	if _, err := os.Stat(filepath.Join(b.outputDir, "script.js")); err == nil {
+		t.Error("script.js written without fingerprinting")
+	}
+}
+
+func TestScriptFingerprinted(t *testing.T) {
+	writeSite(t, map[string]string{"index.md": "---\ntitle: Home\n---\nHello\n"})
+	cfg := config.Default()
+	cfg.Fingerprint = true
+	b, _ := buildSite(t, cfg)
+
+	hashed := b.fingerprints["script.js"]
+	if hashed == "" || hashed == "script.js" {
+		t.Fatalf("script.js isn't fingerprinted: %v", b.fingerprints)
+	}
+	script := readOutput(t, b, hashed)
+	if !strings.Contains(script, "document.addEventListener('DOMContentLoaded'") {
+		t.Errorf("%s doesn't hold the site script", hashed)
+	}
+
+	page := readOutput(t, b, "index.html")
+	if !strings.Contains(page, `<script src="/`+hashed+`" defer></script>`) {
+		t.Errorf("index.html doesn't link to %s", hashed)
+	}
+	if strings.Contains(page, "document.addEventListener('DOMContentLoaded'") {
+		t.Error("index.html inlines the script as well as linking to it")
+	}
+}
@@ -1,6 +1,7 @@
 package build
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -8,7 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,41 +28,63 @@ import (
 type Options struct {
 	IncludeDrafts bool
 	Verbose       bool
-	SkipClean     bool // Skip cleaning output directory (for hot reload)
+	SkipClean     bool   // Skip cleaning output directory (for hot reload)
+	OutputDir     string // Build here instead of the configured outputDir (e.g., a temp dir)
+	Strict        bool   // Fail the build on frontmatter schema violations
 }
 
 // Stats contains build statistics
 type Stats struct {
-	PageCount    int
-	WarningCount int
+	PageCount        int
+	WarningCount     int
+	UnpublishedLinks []string          // Links and embeds to unpublished notes, rendered as plain text
+	SchemaViolations []SchemaViolation // Frontmatter that breaks the configured schema
 }
 
 // Builder handles site generation
 type Builder struct {
-	cfg       *config.Config
-	opts      Options
-	rootDir   string
-	outputDir string
-	templates *templates.Templates
+	cfg        *config.Config
+	opts       Options
+	rootDir    string
+	outputDir  string
+	templates  *templates.Templates
+	shortcodes *templates.Shortcodes
 
 	// Cached state for incremental builds
 	pages          []*content.Page
 	pagesByPath    map[string]*content.Page   // SourcePath -> Page
 	pagesBySlug    map[string]*content.Page   // Slug -> Page
 	pagesBySection map[string][]*content.Page // Section -> Pages (for fast section lookups)
-	pagesByTag     map[string][]*content.Page // Tag (lowercase) -> Pages (for fast tag lookups)
+	pagesByTag     map[string][]*content.Page // Tag (content.NormalizeTag) -> Pages (for fast tag lookups)
 	linkResolver   *content.LinkResolver      // Cached link resolver
+	attachments    *content.AttachmentIndex   // Non-markdown files that notes can embed
+	images         *imageProcessor            // Responsive image variants for the current build
+	unpublished    []*content.Page            // Notes left out in optIn publish mode
+	redirects      map[string]bool            // Output paths of the redirect stubs written
 	siteData       templates.SiteData
+
+	// Commit times when dates is "git", read once per full build
+	gitDates map[string]content.FileDates
+
+	// Self-hosted font faces when fontSource is "local"
+	fontFaces []fontFace
+
+	// Original -> fingerprinted asset names when fingerprint is on (nil otherwise)
+	fingerprints map[string]string
 }
 
 // New creates a new Builder
 func New(cfg *config.Config, opts Options) *Builder {
 	cwd, _ := os.Getwd()
+	outputDir := filepath.Join(cwd, cfg.OutputDir)
+	if opts.OutputDir != "" {
+		outputDir = opts.OutputDir
+	}
 	return &Builder{
 		cfg:       cfg,
 		opts:      opts,
 		rootDir:   cwd,
-		outputDir: filepath.Join(cwd, cfg.OutputDir),
+		outputDir: outputDir,
 	}
 }
 
@@ -75,6 +100,16 @@ func (b *Builder) logTiming(label string, d time.Duration) {
 	}
 }
 
+// logWarnings prints warnings in verbose mode and returns how many there are
+func (b *Builder) logWarnings(warnings []string) int {
+	if b.opts.Verbose {
+		for _, w := range warnings {
+			fmt.Printf("  warning: %s\n", w)
+		}
+	}
+	return len(warnings)
+}
+
 // Build generates the static site
 func (b *Builder) Build() (*Stats, error) {
 	stats := &Stats{}
@@ -83,10 +118,14 @@ func (b *Builder) Build() (*Stats, error) {
 	// Initialize templates
 	t0 = time.Now()
 	var err error
-	b.templates, err = templates.New()
+	b.templates, err = templates.New(b.layoutDirs()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize templates: %w", err)
 	}
+	b.shortcodes, err = templates.NewShortcodes(extractBasePath(b.cfg.BaseURL), b.layoutDirs()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize shortcodes: %w", err)
+	}
 	b.logTiming("templates", time.Since(t0))
 
 	// Clean output directory (skip for hot reload)
@@ -101,13 +140,38 @@ func (b *Builder) Build() (*Stats, error) {
 	}
 	b.logTiming("clean", time.Since(t0))
 
+	// Read commit dates from git history (incremental rebuilds reuse them)
+	var scanWarnings []string
+	b.gitDates = nil
+	if b.cfg.Dates == "git" {
+		t0 = time.Now()
+		dates, shallow, err := content.GitDates(b.rootDir)
+		switch {
+		case err != nil:
+			scanWarnings = append(scanWarnings, fmt.Sprintf("git dates unavailable, using file times: %v", err))
+		case shallow:
+			scanWarnings = append(scanWarnings, "shallow git clone: creation dates only go back to the oldest fetched commit")
+		}
+		b.gitDates = dates
+		b.logTiming("git dates", time.Since(t0))
+	}
+
 	// Scan content
 	t0 = time.Now()
-	scanner := content.NewScanner(b.rootDir, b.cfg.Ignore)
+	scanner := b.newScanner()
 	pages, err := scanner.Scan()
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan content: %w", err)
 	}
+	b.attachments = content.NewAttachmentIndex(scanner.Attachments())
+	for _, path := range scanner.Skipped() {
+		dir := strings.Split(filepath.ToSlash(path), "/")[0]
+		holds := "templates"
+		if dir == "themes" {
+			holds = "themes"
+		}
+		scanWarnings = append(scanWarnings, fmt.Sprintf("%s is not published: %s/ is reserved for %s", path, dir, holds))
+	}
 	b.logTiming("scan", time.Since(t0))
 
 	// Filter drafts
@@ -115,6 +179,27 @@ func (b *Builder) Build() (*Stats, error) {
 		pages = filterDrafts(pages)
 	}
 
+	// In optIn mode, only build notes that ask to be published
+	b.unpublished = nil
+	if b.cfg.PublishMode == "optIn" {
+		pages, b.unpublished = filterUnpublished(pages, b.cfg.PublishFolders)
+	}
+
+	// Two pages with one URL would overwrite each other
+	if err := content.CheckSlugs(pages); err != nil {
+		return nil, err
+	}
+
+	// Check frontmatter against the configured schema
+	stats.SchemaViolations = validateSchemas(pages, b.cfg.Schema)
+	if b.opts.Strict && len(stats.SchemaViolations) > 0 {
+		lines := make([]string, len(stats.SchemaViolations))
+		for i, v := range stats.SchemaViolations {
+			lines[i] = "  " + v.String()
+		}
+		return nil, fmt.Errorf("%d frontmatter schema violations:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
 	// Build section index for O(1) lookups
 	b.pagesBySection = buildSectionIndex(pages)
 
@@ -122,7 +207,7 @@ func (b *Builder) Build() (*Stats, error) {
 	b.pagesByTag = buildTagIndex(pages)
 
 	// Create link resolver once (reused for backlinks, rendering, graph)
-	b.linkResolver = content.NewLinkResolver(pages)
+	b.linkResolver = b.newLinkResolver(pages)
 
 	// Build backlinks (if enabled)
 	t0 = time.Now()
@@ -136,8 +221,29 @@ func (b *Builder) Build() (*Stats, error) {
 
 	// Render markdown to HTML
 	t0 = time.Now()
-	warnings := content.RenderPages(pages, b.cfg.Wikilinks, b.linkResolver, basePath)
+	warnings := append(scanWarnings, content.RenderPages(pages, b.linkResolver, b.renderOptions(basePath))...)
 	b.logTiming("markdown", time.Since(t0))
+
+	// Add srcset and intrinsic dimensions to local images, when widths are configured
+	t0 = time.Now()
+	b.images = nil
+	if len(b.cfg.Images.Widths) > 0 {
+		b.images = newImageProcessor(b.rootDir, b.outputDir, basePath, b.overrideDirs(), b.cfg.Images)
+		warnings = append(warnings, b.processImages(pages)...)
+	}
+	b.logTiming("images", time.Since(t0))
+
+	// Find self-hosted fonts
+	b.fontFaces = nil
+	if b.cfg.Theme.FontSource == "local" {
+		faces, fontWarnings, err := b.findFonts()
+		if err != nil {
+			return nil, err
+		}
+		b.fontFaces = faces
+		warnings = append(warnings, fontWarnings...)
+	}
+	warnings, stats.UnpublishedLinks = splitUnpublishedLinks(warnings)
 	stats.WarningCount = len(warnings)
 
 	if b.opts.Verbose {
@@ -148,18 +254,63 @@ func (b *Builder) Build() (*Stats, error) {
 
 	// Generate site data
 	siteData := templates.SiteData{
-		Title:       b.cfg.Title,
-		Description: b.cfg.Description,
-		Author:      b.cfg.Author,
-		Nav:         b.cfg.Nav,
-		Theme:       b.cfg.Theme,
-		BaseURL:     b.cfg.BaseURL,
-		BasePath:    basePath,
-		Image:       b.cfg.Image,
-		TOC:         b.cfg.TOC,
-		Graph:       b.cfg.Graph,
-		Search:      b.cfg.Search,
-		HeadExtra:   b.cfg.HeadExtra,
+		Title:        b.cfg.Title,
+		Description:  b.cfg.Description,
+		Author:       b.cfg.Author,
+		Nav:          b.cfg.Nav,
+		Theme:        b.cfg.Theme,
+		BaseURL:      b.cfg.BaseURL,
+		BasePath:     basePath,
+		Image:        b.cfg.Image,
+		TOC:          b.cfg.TOC,
+		Graph:        b.cfg.Graph,
+		Search:       b.cfg.Search,
+		HeadExtra:    b.cfg.HeadExtra,
+		MetaParams:   b.cfg.MetaParams,
+		FontPreloads: fontPreloads(b.fontFaces, b.cfg.Theme.FontBody, b.cfg.Theme.FontHeading),
+	}
+
+	// Copy static files. Assets are written before pages, which link to
+	// them by their fingerprinted names.
+	t0 = time.Now()
+	b.fingerprints = nil
+	if b.cfg.Fingerprint {
+		b.fingerprints = make(map[string]string)
+	}
+	if err := b.copyStatic(); err != nil {
+		return nil, fmt.Errorf("failed to copy static files: %w", err)
+	}
+	if err := b.copyAttachments(pages); err != nil {
+		return nil, fmt.Errorf("failed to copy attachments: %w", err)
+	}
+	if err := b.copyFonts(b.fontFaces); err != nil {
+		return nil, fmt.Errorf("failed to copy fonts: %w", err)
+	}
+	b.logTiming("static", time.Since(t0))
+
+	// Generate CSS and JS
+	t0 = time.Now()
+	if err := b.generateCSS(); err != nil {
+		return nil, fmt.Errorf("failed to generate CSS: %w", err)
+	}
+	if err := b.generateJS(&siteData); err != nil {
+		return nil, fmt.Errorf("failed to generate JS: %w", err)
+	}
+	b.logTiming("css", time.Since(t0))
+
+	// Write the asset manifest and cache headers
+	if b.fingerprints != nil {
+		headerWarnings, err := b.writeAssetManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to write asset manifest: %w", err)
+		}
+		stats.WarningCount += len(headerWarnings)
+		if b.opts.Verbose {
+			for _, w := range headerWarnings {
+				fmt.Printf("  warning: %s\n", w)
+			}
+		}
+		siteData.Assets = b.fingerprints
 	}
 
 	// Cache state for incremental builds
@@ -236,20 +387,6 @@ func (b *Builder) Build() (*Stats, error) {
 	}
 	b.logTiming("tags", time.Since(t0))
 
-	// Copy static files
-	t0 = time.Now()
-	if err := b.copyStatic(); err != nil {
-		return nil, fmt.Errorf("failed to copy static files: %w", err)
-	}
-	b.logTiming("static", time.Since(t0))
-
-	// Generate CSS
-	t0 = time.Now()
-	if err := b.generateCSS(); err != nil {
-		return nil, fmt.Errorf("failed to generate CSS: %w", err)
-	}
-	b.logTiming("css", time.Since(t0))
-
 	// Copy favicons
 	t0 = time.Now()
 	if err := b.copyFavicons(); err != nil {
@@ -294,6 +431,21 @@ func (b *Builder) Build() (*Stats, error) {
 	}
 	b.logTiming("rss", time.Since(t0))
 
+	// Generate redirect stubs for aliases and redirect_from, last so they
+	// can't overwrite anything else the build writes
+	t0 = time.Now()
+	redirectWarnings, err := b.generateRedirects(pages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate redirects: %w", err)
+	}
+	stats.WarningCount += len(redirectWarnings)
+	if b.opts.Verbose {
+		for _, w := range redirectWarnings {
+			fmt.Printf("  warning: %s\n", w)
+		}
+	}
+	b.logTiming("redirects", time.Since(t0))
+
 	return stats, nil
 }
 
@@ -310,9 +462,47 @@ const (
 type IncrementalStats struct {
 	PagesRebuilt int
 	TagsRebuilt  int
+	WarningCount int
 	FullRebuild  bool
 }
 
+// Config returns the site config, which is reloaded when leafpress.json or the theme changes
+func (b *Builder) Config() *config.Config {
+	return b.cfg
+}
+
+// ThemeDir returns the absolute directory of the configured theme, or "" if there is none
+func (b *Builder) ThemeDir() string {
+	themeDir := b.cfg.Theme.Dir(b.rootDir)
+	if themeDir == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(themeDir); err == nil {
+		return abs
+	}
+	return themeDir
+}
+
+// inThemeDir reports whether a path is inside the configured theme, which
+// can be a folder in themes/ or anywhere else
+func (b *Builder) inThemeDir(path string) bool {
+	themeDir := b.ThemeDir()
+	if themeDir == "" {
+		return false
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return isWithin(themeDir, path)
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // RebuildIncremental performs an incremental rebuild based on changed file
 func (b *Builder) RebuildIncremental(changedPath string, changeType ChangeType) (*IncrementalStats, error) {
 	stats := &IncrementalStats{}
@@ -333,8 +523,8 @@ func (b *Builder) RebuildIncremental(changedPath string, changeType ChangeType)
 		relPath = changedPath
 	}
 
-	// Check if it's a config change - requires full rebuild with fresh config
-	if filepath.Base(relPath) == "leafpress.json" {
+	// Check if it's a config or theme change - requires full rebuild with fresh config
+	if filepath.Base(relPath) == "leafpress.json" || b.inThemeDir(changedPath) {
 		// Reload config from disk
 		newCfg, err := config.Load("leafpress.json")
 		if err != nil {
@@ -343,14 +533,18 @@ func (b *Builder) RebuildIncremental(changedPath string, changeType ChangeType)
 		b.cfg = newCfg
 		b.outputDir = filepath.Join(b.rootDir, b.cfg.OutputDir)
 
-		// Regenerate templates
-		newTemplates, err := templates.New()
-		if err != nil {
-			return nil, fmt.Errorf("failed to reload templates: %w", err)
+		// Build parses the templates again, from the new theme's layouts
+		b.opts.SkipClean = false // Full clean for config changes
+		if _, err := b.Build(); err != nil {
+			return nil, err
 		}
-		b.templates = newTemplates
+		stats.FullRebuild = true
+		return stats, nil
+	}
 
-		b.opts.SkipClean = false // Full clean for config changes
+	// Layouts are parsed with the templates, and fonts are preloaded by every
+	// page, so every page needs rendering again
+	if slashPath := filepath.ToSlash(relPath); slashPath == "layouts" || strings.HasPrefix(slashPath, "layouts/") || strings.HasPrefix(slashPath, "fonts/") {
 		if _, err := b.Build(); err != nil {
 			return nil, err
 		}
@@ -360,11 +554,24 @@ func (b *Builder) RebuildIncremental(changedPath string, changeType ChangeType)
 
 	// Check if it's a static file (cross-platform: handle both / and \ separators)
 	isStaticFile := strings.HasPrefix(relPath, "static/") || strings.HasPrefix(relPath, "static"+string(filepath.Separator))
+
+	// Fingerprinted assets get a new name, so pages need their links updated
+	_, fingerprinted := b.fingerprints[filepath.ToSlash(relPath)]
+	if fingerprinted || (b.cfg.Fingerprint && relPath == "style.css") {
+		if _, err := b.Build(); err != nil {
+			return nil, err
+		}
+		stats.FullRebuild = true
+		return stats, nil
+	}
 	if isStaticFile {
 		t0 = time.Now()
 		if err := b.copyStatic(); err != nil {
 			return nil, err
 		}
+		if err := b.images.refresh(relPath); err != nil {
+			return nil, err
+		}
 		b.logTiming("static", time.Since(t0))
 		return stats, nil
 	}
@@ -384,6 +591,31 @@ func (b *Builder) RebuildIncremental(changedPath string, changeType ChangeType)
 		return b.rebuildMarkdownFile(relPath, changeType)
 	}
 
+	// Handle attachment changes: edits to embedded files are copied over, while
+	// new or removed files that embeds name change how they resolve and need a
+	// full rebuild. Files no note embeds are never published.
+	if content.IsAttachmentPath(relPath, b.scanIgnore()) {
+		if changeType == ChangeModify && b.embedsAttachment(relPath) {
+			t0 = time.Now()
+			if err := b.copyAttachment(relPath); err != nil {
+				return nil, err
+			}
+			if err := b.images.refresh(relPath); err != nil {
+				return nil, err
+			}
+			b.logTiming("attachment", time.Since(t0))
+			return stats, nil
+		}
+		if !b.embedNamesFile(relPath) {
+			return stats, nil
+		}
+		if _, err := b.Build(); err != nil {
+			return nil, err
+		}
+		stats.FullRebuild = true
+		return stats, nil
+	}
+
 	return stats, nil
 }
 
@@ -407,7 +639,7 @@ func (b *Builder) rebuildMarkdownFile(relPath string, changeType ChangeType) (*I
 
 	// Parse only the changed file (not full scan)
 	t0 = time.Now()
-	changedPage, err := content.ParseSingleFile(b.rootDir, relPath)
+	changedPage, err := b.newScanner().ParseFile(relPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse %s: %w", relPath, err)
 	}
@@ -422,8 +654,16 @@ func (b *Builder) rebuildMarkdownFile(relPath string, changeType ChangeType) (*I
 	}
 	b.logTiming("parse", time.Since(t0))
 
+	// Private notes in optIn mode
+	if unpublishedStats, err := b.rebuildUnpublished(relPath, changedPage); unpublishedStats != nil || err != nil {
+		return unpublishedStats, err
+	}
+
 	// Get the old page if it existed
 	oldPage := b.pagesByPath[relPath]
+	if err := content.CheckSlugs(append(slices.Clone(b.pages), changedPage)); err != nil {
+		return nil, err
+	}
 
 	// Update the pages cache with the new/changed page
 	if oldPage != nil {
@@ -469,14 +709,14 @@ func (b *Builder) rebuildMarkdownFile(relPath string, changeType ChangeType) (*I
 		// If tags changed, rebuild affected tag pages
 		oldTags := make(map[string]bool)
 		for _, t := range oldPage.Tags {
-			oldTags[strings.ToLower(t)] = true
+			oldTags[content.NormalizeTag(t)] = true
 		}
 		for _, t := range changedPage.Tags {
-			tLower := strings.ToLower(t)
-			if !oldTags[tLower] {
-				tagsToRebuild[tLower] = true // New tag
+			key := content.NormalizeTag(t)
+			if !oldTags[key] {
+				tagsToRebuild[key] = true // New tag
 			}
-			delete(oldTags, tLower)
+			delete(oldTags, key)
 		}
 		for t := range oldTags {
 			tagsToRebuild[t] = true // Removed tag
@@ -486,12 +726,29 @@ func (b *Builder) rebuildMarkdownFile(relPath string, changeType ChangeType) (*I
 		rebuildSectionIndex = true
 		// All tags are new
 		for _, t := range changedPage.Tags {
-			tagsToRebuild[strings.ToLower(t)] = true
+			tagsToRebuild[content.NormalizeTag(t)] = true
 		}
 	}
 
+	// Pages that embedded the old version must pick up its new content
+	if oldPage != nil {
+		for _, embedder := range b.embeddersOf(oldPage) {
+			pagesToRebuild[embedder.SourcePath] = embedder
+		}
+	}
+
+	// Pages whose queries list the page, before or after the change
+	for _, querier := range b.queriersOf(oldPage, changedPage) {
+		pagesToRebuild[querier.SourcePath] = querier
+	}
+
 	// Update the cached resolver with current pages
-	b.linkResolver = content.NewLinkResolver(b.pages)
+	b.linkResolver = b.newLinkResolver(b.pages)
+
+	// Pages that now embed the changed page (e.g. a previously broken embed)
+	for _, embedder := range b.embeddersOf(changedPage) {
+		pagesToRebuild[embedder.SourcePath] = embedder
+	}
 
 	// Rebuild backlinks with updated page set and fresh resolver
 	t0 = time.Now()
@@ -520,7 +777,11 @@ func (b *Builder) rebuildMarkdownFile(relPath string, changeType ChangeType) (*I
 			}
 		}
 	}
-	content.RenderPages(pagesToRender, b.cfg.Wikilinks, b.linkResolver, b.siteData.BasePath)
+	content.RenderPages(pagesToRender, b.linkResolver, b.renderOptions(b.siteData.BasePath))
+	stats.WarningCount += b.logWarnings(b.processImages(pagesToRender))
+	if err := b.copyAttachments([]*content.Page{changedPage}); err != nil {
+		return nil, err
+	}
 	b.logTiming("markdown", time.Since(t0))
 
 	// Render the affected pages
@@ -539,6 +800,14 @@ func (b *Builder) rebuildMarkdownFile(relPath string, changeType ChangeType) (*I
 	}
 	b.logTiming("render", time.Since(t0))
 
+	// Refresh redirect stubs for the changed page
+	if oldPage != nil {
+		b.removeRedirects(oldPage)
+	}
+	if _, err := b.writeRedirects(changedPage, nil); err != nil {
+		return nil, err
+	}
+
 	// Rebuild section index if needed
 	if rebuildSectionIndex && sectionSlug != "" {
 		t0 = time.Now()
@@ -584,6 +853,11 @@ func (b *Builder) rebuildMarkdownFile(relPath string, changeType ChangeType) (*I
 func (b *Builder) handleDeletedFile(relPath string) (*IncrementalStats, error) {
 	stats := &IncrementalStats{}
 
+	// Private notes in optIn mode
+	if unpublishedStats, err := b.rebuildUnpublished(relPath, nil); unpublishedStats != nil || err != nil {
+		return unpublishedStats, err
+	}
+
 	oldPage := b.pagesByPath[relPath]
 	if oldPage == nil {
 		return stats, nil // File wasn't tracked
@@ -595,15 +869,24 @@ func (b *Builder) handleDeletedFile(relPath string) (*IncrementalStats, error) {
 	// Also try to remove the parent directory if empty
 	os.Remove(filepath.Dir(outPath))
 
-	// Rebuild pages that had backlinks to this page
+	// Rebuild pages that had backlinks to this page, embedded it or listed it in a query
 	pagesToRebuild := make([]*content.Page, 0)
+	seen := make(map[*content.Page]bool)
 	for _, backlinker := range oldPage.Backlinks {
+		seen[backlinker] = true
 		pagesToRebuild = append(pagesToRebuild, backlinker)
 	}
+	for _, dependent := range append(b.embeddersOf(oldPage), b.queriersOf(oldPage)...) {
+		if !seen[dependent] && dependent != oldPage {
+			seen[dependent] = true
+			pagesToRebuild = append(pagesToRebuild, dependent)
+		}
+	}
 
 	// Remove from cached state
 	delete(b.pagesByPath, relPath)
 	delete(b.pagesBySlug, oldPage.Slug)
+	b.removeRedirects(oldPage)
 
 	// Filter out the deleted page from pages slice
 	newPages := make([]*content.Page, 0, len(b.pages)-1)
@@ -615,13 +898,14 @@ func (b *Builder) handleDeletedFile(relPath string) (*IncrementalStats, error) {
 	b.pages = newPages
 
 	// Update resolver and rebuild backlinks
-	b.linkResolver = content.NewLinkResolver(b.pages)
+	b.linkResolver = b.newLinkResolver(b.pages)
 	if b.cfg.Backlinks {
 		content.BuildBacklinks(b.pages, b.linkResolver)
 	}
 
 	// Re-render affected pages
-	content.RenderPages(pagesToRebuild, b.cfg.Wikilinks, b.linkResolver, b.siteData.BasePath)
+	content.RenderPages(pagesToRebuild, b.linkResolver, b.renderOptions(b.siteData.BasePath))
+	stats.WarningCount += b.logWarnings(b.processImages(pagesToRebuild))
 	for _, page := range pagesToRebuild {
 		if page.IsIndex {
 			if err := b.renderSectionIndex(page, b.pages, b.siteData); err != nil {
@@ -638,7 +922,7 @@ func (b *Builder) handleDeletedFile(relPath string) (*IncrementalStats, error) {
 	// Rebuild tag pages that contained this page
 	tagsToRebuild := make(map[string]bool)
 	for _, t := range oldPage.Tags {
-		tagsToRebuild[strings.ToLower(t)] = true
+		tagsToRebuild[content.NormalizeTag(t)] = true
 	}
 	if len(tagsToRebuild) > 0 {
 		if err := b.rebuildTagPages(tagsToRebuild, b.pages); err != nil {
@@ -665,6 +949,99 @@ func (b *Builder) handleDeletedFile(relPath string) (*IncrementalStats, error) {
 	return stats, nil
 }
 
+// rebuildUnpublished handles a change to a note that is private in optIn mode,
+// before or after the change (changedPage is nil for deletions). Edits that
+// keep a note private only update the cached note. Publishing or unpublishing
+// a note changes how other notes link to it, so it triggers a full rebuild.
+// Returns nil stats if the change only concerns published notes.
+func (b *Builder) rebuildUnpublished(relPath string, changedPage *content.Page) (*IncrementalStats, error) {
+	if b.cfg.PublishMode != "optIn" {
+		return nil, nil
+	}
+
+	idx := -1
+	for i, p := range b.unpublished {
+		if p.SourcePath == relPath {
+			idx = i
+			break
+		}
+	}
+	published := changedPage != nil && isPublished(changedPage, b.cfg.PublishFolders)
+
+	switch {
+	case idx < 0 && (changedPage == nil || published):
+		return nil, nil
+	case idx >= 0 && changedPage != nil && !published:
+		// Links only depend on the slug and aliases
+		oldPage := b.unpublished[idx]
+		if oldPage.Slug == changedPage.Slug && slices.Equal(oldPage.Aliases, changedPage.Aliases) {
+			b.unpublished[idx] = changedPage
+			b.linkResolver.SetUnpublished(b.unpublished)
+			return &IncrementalStats{}, nil
+		}
+	}
+
+	if _, err := b.Build(); err != nil {
+		return nil, err
+	}
+	return &IncrementalStats{FullRebuild: true}, nil
+}
+
+// newLinkResolver creates a link resolver for pages that also knows the unpublished notes
+func (b *Builder) newLinkResolver(pages []*content.Page) *content.LinkResolver {
+	resolver := content.NewLinkResolver(pages)
+	resolver.SetUnpublished(b.unpublished)
+	return resolver
+}
+
+// embeddersOf returns pages that transclude target, directly or through other embeds
+func (b *Builder) embeddersOf(target *content.Page) []*content.Page {
+	var result []*content.Page
+	seen := map[*content.Page]bool{target: true}
+	queue := []*content.Page{target}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, p := range b.pages {
+			if seen[p] {
+				continue
+			}
+			for _, embed := range content.ExtractEmbeds(p.RawContent) {
+				if b.linkResolver.Resolve(embed.Target).Page == current {
+					seen[p] = true
+					result = append(result, p)
+					queue = append(queue, p)
+					break
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// queriersOf returns pages with a leafpress-query block that matches any of the given pages
+func (b *Builder) queriersOf(pages ...*content.Page) []*content.Page {
+	var result []*content.Page
+	for _, p := range b.pages {
+		for _, query := range content.ExtractQueries(p.RawContent) {
+			matched := false
+			for _, target := range pages {
+				if target != nil && target != p && query.Matches(target) {
+					matched = true
+					break
+				}
+			}
+			if matched {
+				result = append(result, p)
+				break
+			}
+		}
+	}
+	return result
+}
+
 // rebuildAutoIndex rebuilds a single auto-generated index
 func (b *Builder) rebuildAutoIndex(sectionSlug string, pages []*content.Page) error {
 	sectionPages := b.getSectionPagesFromIndex(sectionSlug)
@@ -700,12 +1077,25 @@ func (b *Builder) rebuildTagPages(tags map[string]bool, pages []*content.Page) e
 
 	tagsDir := filepath.Join(b.outputDir, "tags")
 
+	// Parent tags roll up their descendants' pages, so they change too
+	affected := make(map[string]bool)
 	for tag := range tags {
-		tagDir := filepath.Join(tagsDir, tag)
+		tag = content.NormalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+		affected[tag] = true
+		for _, parent := range tagAncestors(tag) {
+			affected[parent] = true
+		}
+	}
+
+	for tag := range affected {
+		tagDir := filepath.Join(tagsDir, filepath.FromSlash(tag))
 		pagesForTag := b.pagesByTag[tag]
 
 		if len(pagesForTag) == 0 {
-			// Tag no longer has any pages, remove it
+			// Tag no longer has any pages (nor do its descendants), remove it
 			os.RemoveAll(tagDir)
 			continue
 		}
@@ -722,30 +1112,13 @@ func (b *Builder) rebuildTagPages(tags map[string]bool, pages []*content.Page) e
 			return err
 		}
 
-		if err := b.templates.RenderTagPage(f, templates.TagPageData{
-			Site:        b.siteData,
-			Tag:         tag,
-			Pages:       pagesForTag,
-			CurrentPath: "/tags/" + tag + "/",
-		}); err != nil {
+		if err := b.templates.RenderTagPage(f, tagPageData(tag, pagesForTag, b.pagesByTag, b.siteData)); err != nil {
 			f.Close()
 			return err
 		}
 		f.Close()
 	}
 
-	// Rebuild tag index using cached pagesByTag
-	var allTags []templates.TagInfo
-	for tag, taggedPages := range b.pagesByTag {
-		allTags = append(allTags, templates.TagInfo{
-			Name:  tag,
-			Count: len(taggedPages),
-		})
-	}
-	sort.Slice(allTags, func(i, j int) bool {
-		return allTags[i].Name < allTags[j].Name
-	})
-
 	if err := os.MkdirAll(tagsDir, 0755); err != nil {
 		return err
 	}
@@ -757,9 +1130,10 @@ func (b *Builder) rebuildTagPages(tags map[string]bool, pages []*content.Page) e
 	}
 	defer f.Close()
 
+	// Rebuild tag index using cached pagesByTag
 	return b.templates.RenderTagIndex(f, templates.TagIndexData{
 		Site:        b.siteData,
-		Tags:        allTags,
+		Tags:        buildTagTree(b.pagesByTag),
 		CurrentPath: "/tags/",
 	})
 }
@@ -965,18 +1339,7 @@ func (b *Builder) generateTagPages(pages []*content.Page, siteData templates.Sit
 		return err
 	}
 
-	// Generate tag index
-	var tags []templates.TagInfo
-	for tag, pages := range tagPages {
-		tags = append(tags, templates.TagInfo{
-			Name:  tag,
-			Count: len(pages),
-		})
-	}
-	sort.Slice(tags, func(i, j int) bool {
-		return tags[i].Name < tags[j].Name
-	})
-
+	// Generate tag index as a tree of nested tags
 	indexPath := filepath.Join(tagsDir, "index.html")
 	f, err := os.Create(indexPath)
 	if err != nil {
@@ -985,7 +1348,7 @@ func (b *Builder) generateTagPages(pages []*content.Page, siteData templates.Sit
 
 	if err := b.templates.RenderTagIndex(f, templates.TagIndexData{
 		Site:        siteData,
-		Tags:        tags,
+		Tags:        buildTagTree(tagPages),
 		CurrentPath: "/tags/",
 	}); err != nil {
 		f.Close()
@@ -1019,7 +1382,7 @@ func (b *Builder) generateTagPages(pages []*content.Page, siteData templates.Sit
 			for job := range jobChan {
 				sortPages(job.pages, "date")
 
-				tagDir := filepath.Join(tagsDir, job.tag)
+				tagDir := filepath.Join(tagsDir, filepath.FromSlash(job.tag))
 				if err := os.MkdirAll(tagDir, 0755); err != nil {
 					errChan <- err
 					continue
@@ -1032,12 +1395,7 @@ func (b *Builder) generateTagPages(pages []*content.Page, siteData templates.Sit
 					continue
 				}
 
-				if err := b.templates.RenderTagPage(f, templates.TagPageData{
-					Site:        siteData,
-					Tag:         job.tag,
-					Pages:       job.pages,
-					CurrentPath: "/tags/" + job.tag + "/",
-				}); err != nil {
+				if err := b.templates.RenderTagPage(f, tagPageData(job.tag, job.pages, tagPages, siteData)); err != nil {
 					f.Close()
 					errChan <- err
 					continue
@@ -1065,27 +1423,247 @@ func (b *Builder) generateTagPages(pages []*content.Page, siteData templates.Sit
 	return nil
 }
 
-// copyStatic copies the static directory
-func (b *Builder) copyStatic() error {
-	srcDir := filepath.Join(b.rootDir, "static")
-	if _, err := os.Stat(srcDir); os.IsNotExist(err) {
-		return nil // No static directory
+// generateRedirects writes redirect stubs for every page's aliases and redirect_from
+// entries, returning warnings for ambiguous aliases and conflicting redirects
+func (b *Builder) generateRedirects(pages []*content.Page) ([]string, error) {
+	var warnings []string
+
+	for alias, claimants := range b.linkResolver.AmbiguousAliases() {
+		var paths []string
+		for _, p := range claimants {
+			paths = append(paths, p.SourcePath)
+		}
+		sort.Strings(paths)
+		warnings = append(warnings, fmt.Sprintf("ambiguous alias %q used by %s", alias, strings.Join(paths, ", ")))
+	}
+	sort.Strings(warnings)
+
+	b.redirects = make(map[string]bool)
+	claimed := make(map[string]*content.Page)
+	for _, page := range pages {
+		pageWarnings, err := b.writeRedirects(page, claimed)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, pageWarnings...)
+	}
+
+	return warnings, nil
+}
+
+// writeRedirects writes the redirect stubs for a single page. claimed tracks
+// which page owns each redirect slug so the first claimant wins (may be nil).
+func (b *Builder) writeRedirects(page *content.Page, claimed map[string]*content.Page) ([]string, error) {
+	var warnings []string
+	basePath := extractBasePath(b.cfg.BaseURL)
+	target := basePath + page.Permalink
+
+	for _, slug := range page.RedirectSlugs() {
+		if claimed != nil {
+			if other, ok := claimed[slug]; ok {
+				warnings = append(warnings, fmt.Sprintf("redirect /%s/ claimed by both %s and %s", slug, other.SourcePath, page.SourcePath))
+				continue
+			}
+			claimed[slug] = page
+		}
+
+		// Never overwrite a real page
+		if b.pagesBySlug[slug] != nil {
+			warnings = append(warnings, fmt.Sprintf("redirect /%s/ in %s conflicts with an existing page", slug, page.SourcePath))
+			continue
+		}
+
+		// Nor any other file the build wrote there (tag pages, section indexes, ...)
+		outPath := filepath.Join(b.outputDir, filepath.FromSlash(slug), "index.html")
+		if !isWithin(b.outputDir, outPath) {
+			warnings = append(warnings, fmt.Sprintf("redirect /%s/ in %s is outside the output directory", slug, page.SourcePath))
+			continue
+		}
+		if _, err := os.Stat(outPath); err == nil && !b.redirects[outPath] {
+			warnings = append(warnings, fmt.Sprintf("redirect /%s/ in %s conflicts with a generated file", slug, page.SourcePath))
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			return nil, err
+		}
+		b.redirects[outPath] = true
+
+		f, err := os.Create(outPath)
+		if err != nil {
+			return nil, err
+		}
+
+		canonical := ""
+		if b.cfg.BaseURL != "" {
+			canonical = strings.TrimSuffix(b.cfg.BaseURL, "/") + page.Permalink
+		}
+		if err := b.templates.RenderRedirect(f, templates.RedirectData{
+			Title:     page.Title,
+			URL:       target,
+			Canonical: canonical,
+		}); err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.Close()
 	}
 
+	return warnings, nil
+}
+
+// removeRedirects deletes the redirect stubs previously written for a page
+func (b *Builder) removeRedirects(page *content.Page) {
+	for _, slug := range page.RedirectSlugs() {
+		if b.pagesBySlug[slug] != nil {
+			continue // A real page lives here now
+		}
+		outPath := filepath.Join(b.outputDir, filepath.FromSlash(slug), "index.html")
+		if !b.redirects[outPath] {
+			continue // Not ours
+		}
+		delete(b.redirects, outPath)
+		os.Remove(outPath)
+		os.Remove(filepath.Dir(outPath))
+	}
+}
+
+// copyStatic copies the theme's static directory, then the site's, so site files win
+func (b *Builder) copyStatic() error {
 	dstDir := filepath.Join(b.outputDir, "static")
-	return copyDir(srcDir, dstDir)
+	for _, dir := range b.overrideDirs() {
+		srcDir := filepath.Join(dir, "static")
+		if _, err := os.Stat(srcDir); os.IsNotExist(err) {
+			continue // No static directory
+		}
+		if err := copyDir(srcDir, dstDir); err != nil {
+			return err
+		}
+	}
+	if b.fingerprints != nil {
+		return b.fingerprintStatic()
+	}
+	return nil
 }
 
-// copyFavicons copies favicons from user directory or uses embedded defaults
+// overrideDirs returns the directories that can override built-in files,
+// lowest priority first: the theme (if any), then the site itself
+func (b *Builder) overrideDirs() []string {
+	if themeDir := b.cfg.Theme.Dir(b.rootDir); themeDir != "" {
+		return []string{themeDir, b.rootDir}
+	}
+	return []string{b.rootDir}
+}
+
+// readOverride reads a file from the site root, or else from the theme directory
+func (b *Builder) readOverride(name string) ([]byte, bool) {
+	dirs := b.overrideDirs()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if data, err := os.ReadFile(filepath.Join(dirs[i], name)); err == nil {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// layoutDirs returns the directories whose templates override the built-in ones,
+// lowest priority first
+func (b *Builder) layoutDirs() []string {
+	var dirs []string
+	for _, dir := range b.overrideDirs() {
+		dirs = append(dirs, filepath.Join(dir, "layouts"))
+	}
+	return dirs
+}
+
+// newScanner creates a content scanner with the site's slug and date settings
+func (b *Builder) newScanner() *content.Scanner {
+	scanner := content.NewScanner(b.rootDir, b.scanIgnore())
+	scanner.SetNormalizeSlugs(b.cfg.Slugs == "normalize")
+	scanner.SetGitDates(b.gitDates)
+	return scanner
+}
+
+// scanIgnore returns the top-level paths the scanner skips: the configured
+// ignore list plus the output directory, so built files aren't indexed as attachments
+func (b *Builder) scanIgnore() []string {
+	return append([]string{b.cfg.OutputDir}, b.cfg.Ignore...)
+}
+
+// copyAttachments copies the vault attachments embedded by pages into the output,
+// keeping their paths. Files under static/ are already copied by copyStatic.
+func (b *Builder) copyAttachments(pages []*content.Page) error {
+	if b.attachments == nil {
+		return nil
+	}
+
+	copied := make(map[*content.Attachment]bool)
+	for _, page := range pages {
+		for _, target := range content.ExtractFileEmbeds(page.RawContent) {
+			attachment := b.attachments.Resolve(target).Attachment
+			if attachment == nil || attachment.Static || copied[attachment] {
+				continue
+			}
+			copied[attachment] = true
+			if err := b.copyAttachment(attachment.SourcePath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// embedsAttachment reports whether a page's file embed resolves to the attachment at relPath
+func (b *Builder) embedsAttachment(relPath string) bool {
+	if b.attachments == nil {
+		return false
+	}
+	for _, page := range b.pages {
+		for _, target := range content.ExtractFileEmbeds(page.RawContent) {
+			attachment := b.attachments.Resolve(target).Attachment
+			if attachment != nil && filepath.ToSlash(attachment.SourcePath) == filepath.ToSlash(relPath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// embedNamesFile reports whether a page's file embed names the file at relPath,
+// whether or not it resolves there yet
+func (b *Builder) embedNamesFile(relPath string) bool {
+	for _, page := range b.pages {
+		for _, target := range content.ExtractFileEmbeds(page.RawContent) {
+			if content.EmbedNames(target, relPath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyAttachment copies a single attachment to the same relative path in the output
+func (b *Builder) copyAttachment(relPath string) error {
+	data, err := os.ReadFile(filepath.Join(b.rootDir, relPath))
+	if err != nil {
+		return err
+	}
+	dstPath := filepath.Join(b.outputDir, relPath)
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dstPath, data, 0644)
+}
+
+// copyFavicons copies favicons from the site or theme directory, or uses embedded defaults
 func (b *Builder) copyFavicons() error {
 	favicons := []string{"favicon.ico", "favicon.svg", "favicon-96x96.png"}
 
 	for _, name := range favicons {
-		userPath := filepath.Join(b.rootDir, name)
 		outPath := filepath.Join(b.outputDir, name)
 
-		// Check if user has provided their own favicon
-		if data, err := os.ReadFile(userPath); err == nil {
+		// Check if the site or theme provides its own favicon
+		if data, ok := b.readOverride(name); ok {
 			// Use user's favicon
 			if err := os.WriteFile(outPath, data, 0644); err != nil {
 				return fmt.Errorf("failed to write %s: %w", name, err)
@@ -1115,15 +1693,78 @@ func (b *Builder) generateCSS() error {
 	// Start with default CSS
 	css := templates.DefaultCSS
 
-	// Append user CSS if exists
+	// Add colors for custom callout types
+	if calloutCSS := customCalloutCSS(b.cfg.Callouts); calloutCSS != "" {
+		css += "\n\n/* Custom Callouts */\n" + calloutCSS
+	}
+
+	// Declare self-hosted fonts
+	if len(b.fontFaces) > 0 {
+		css += "\n\n/* Self-hosted Fonts */\n" + fontFaceCSS(b.fontFaces, b.assetPath)
+	}
+
+	// Append theme CSS, then user CSS, if they exist
+	if themeDir := b.cfg.Theme.Dir(b.rootDir); themeDir != "" {
+		if data, err := os.ReadFile(filepath.Join(themeDir, "style.css")); err == nil {
+			css += "\n\n/* Theme Styles */\n" + string(data)
+		}
+	}
 	userCSS := filepath.Join(b.rootDir, "style.css")
 	if data, err := os.ReadFile(userCSS); err == nil {
 		css += "\n\n/* User Styles */\n" + string(data)
 	}
 
 	// Write combined CSS
-	outPath := filepath.Join(b.outputDir, "style.css")
-	return os.WriteFile(outPath, []byte(css), 0644)
+	return b.writeAsset("style.css", []byte(css))
+}
+
+// generateJS renders the site script, which pages inline. With fingerprinting
+// it's written to script.js instead, so browsers can cache it.
+func (b *Builder) generateJS(siteData *templates.SiteData) error {
+	var buf bytes.Buffer
+	if err := templates.RenderScript(&buf, *siteData); err != nil {
+		return err
+	}
+	if b.fingerprints == nil {
+		siteData.InlineScript = template.JS(buf.String())
+		return nil
+	}
+	return b.writeAsset("script.js", buf.Bytes())
+}
+
+// customCalloutCSS returns the color rules for custom callout types, matching
+// the built-in ones: a tinted border and background with a solid title color
+func customCalloutCSS(callouts map[string]config.CalloutConfig) string {
+	names := make([]string, 0, len(callouts))
+	for name, callout := range callouts {
+		if callout.Color != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		color := callouts[name].Color
+		r, g, bl := hexToRGB(color)
+		// The dark selector keeps built-in dark mode colors from overriding the custom color
+		fmt.Fprintf(&sb, ".lp-callout-%[1]s,\n[data-theme=\"dark\"] .lp-callout-%[1]s {\n", strings.ToLower(name))
+		fmt.Fprintf(&sb, "  --lp-callout-border: rgba(%d, %d, %d, 0.3);\n", r, g, bl)
+		fmt.Fprintf(&sb, "  --lp-callout-bg: rgba(%d, %d, %d, 0.05);\n", r, g, bl)
+		fmt.Fprintf(&sb, "  --lp-callout-title: %s;\n", color)
+		sb.WriteString("}\n\n")
+	}
+	return sb.String()
+}
+
+// hexToRGB converts a validated #rgb or #rrggbb color to its components
+func hexToRGB(hex string) (int, int, int) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	value, _ := strconv.ParseUint(hex, 16, 32)
+	return int(value >> 16 & 0xff), int(value >> 8 & 0xff), int(value & 0xff)
 }
 
 // generateRobotsTxt writes the robots.txt file
@@ -1308,11 +1949,12 @@ func (b *Builder) generateJSONFiles(pages []*content.Page, genGraph, genSearch b
 
 	// Graph types
 	type GraphNode struct {
-		ID     string   `json:"id"`
-		Title  string   `json:"title"`
-		URL    string   `json:"url"`
-		Growth string   `json:"growth,omitempty"`
-		Tags   []string `json:"tags,omitempty"`
+		ID     string         `json:"id"`
+		Title  string         `json:"title"`
+		URL    string         `json:"url"`
+		Growth string         `json:"growth,omitempty"`
+		Tags   []string       `json:"tags,omitempty"`
+		Params map[string]any `json:"params,omitempty"`
 	}
 	type GraphEdge struct {
 		Source string `json:"source"`
@@ -1325,10 +1967,11 @@ func (b *Builder) generateJSONFiles(pages []*content.Page, genGraph, genSearch b
 
 	// Search index type
 	type SearchEntry struct {
-		Title   string   `json:"title"`
-		URL     string   `json:"url"`
-		Content string   `json:"content"`
-		Tags    []string `json:"tags,omitempty"`
+		Title   string         `json:"title"`
+		URL     string         `json:"url"`
+		Content string         `json:"content"`
+		Tags    []string       `json:"tags,omitempty"`
+		Params  map[string]any `json:"params,omitempty"`
 	}
 
 	var graph Graph
@@ -1343,6 +1986,7 @@ func (b *Builder) generateJSONFiles(pages []*content.Page, genGraph, genSearch b
 				URL:    b.siteData.BasePath + page.Permalink,
 				Growth: page.Growth,
 				Tags:   page.Tags,
+				Params: page.CustomParams(),
 			})
 
 			for _, target := range page.OutLinks {
@@ -1362,6 +2006,7 @@ func (b *Builder) generateJSONFiles(pages []*content.Page, genGraph, genSearch b
 				URL:     b.siteData.BasePath + page.Permalink,
 				Content: page.PlainContent(),
 				Tags:    page.Tags,
+				Params:  page.CustomParams(),
 			})
 		}
 	}
@@ -1409,6 +2054,45 @@ func filterDrafts(pages []*content.Page) []*content.Page {
 	return result
 }
 
+// filterUnpublished splits pages into the ones published in optIn mode and the rest
+func filterUnpublished(pages []*content.Page, folders []string) (published, unpublished []*content.Page) {
+	for _, p := range pages {
+		if isPublished(p, folders) {
+			published = append(published, p)
+		} else {
+			unpublished = append(unpublished, p)
+		}
+	}
+	return published, unpublished
+}
+
+// isPublished reports whether a page is published in optIn mode: publish: true,
+// or inside one of folders without publish: false
+func isPublished(page *content.Page, folders []string) bool {
+	if page.Publish != nil {
+		return *page.Publish
+	}
+	sourcePath := filepath.ToSlash(page.SourcePath)
+	for _, folder := range folders {
+		if strings.HasPrefix(sourcePath, strings.Trim(folder, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitUnpublishedLinks separates warnings about links and embeds to unpublished notes
+func splitUnpublishedLinks(warnings []string) (rest, unpublished []string) {
+	for _, w := range warnings {
+		if strings.HasPrefix(w, "unpublished ") {
+			unpublished = append(unpublished, w)
+		} else {
+			rest = append(rest, w)
+		}
+	}
+	return rest, unpublished
+}
+
 // buildSectionIndex creates a map of section -> pages for O(1) lookups
 func buildSectionIndex(pages []*content.Page) map[string][]*content.Page {
 	index := make(map[string][]*content.Page)
@@ -1425,18 +2109,102 @@ func buildSectionIndex(pages []*content.Page) map[string][]*content.Page {
 	return index
 }
 
-// buildTagIndex creates a map of tag (lowercase) -> pages for O(1) lookups
+// buildTagIndex creates a map of tag (lowercase) -> pages for O(1) lookups.
+// Nested tags (area/subarea) also list their pages under every parent tag.
 func buildTagIndex(pages []*content.Page) map[string][]*content.Page {
 	index := make(map[string][]*content.Page)
 	for _, page := range pages {
+		seen := make(map[string]bool)
 		for _, tag := range page.Tags {
-			tagLower := strings.ToLower(tag)
-			index[tagLower] = append(index[tagLower], page)
+			tag = content.NormalizeTag(tag)
+			if tag == "" {
+				continue
+			}
+			for _, t := range append(tagAncestors(tag), tag) {
+				if !seen[t] {
+					seen[t] = true
+					index[t] = append(index[t], page)
+				}
+			}
 		}
 	}
 	return index
 }
 
+// tagAncestors returns the parent tags of a nested tag, outermost first
+// e.g., "area/sub/topic" -> ["area", "area/sub"]
+func tagAncestors(tag string) []string {
+	var ancestors []string
+	for i, c := range tag {
+		if c == '/' {
+			ancestors = append(ancestors, tag[:i])
+		}
+	}
+	return ancestors
+}
+
+// buildTagTree lists tags depth-first, each parent followed by its children,
+// with counts that include pages from descendant tags
+func buildTagTree(tagPages map[string][]*content.Page) []templates.TagInfo {
+	names := make([]string, 0, len(tagPages))
+	for tag := range tagPages {
+		names = append(names, tag)
+	}
+	// Compare segment by segment so "a/b" stays under "a" rather than after "a-c"
+	sort.Slice(names, func(i, j int) bool {
+		a, b := strings.Split(names[i], "/"), strings.Split(names[j], "/")
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	tags := make([]templates.TagInfo, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, newTagInfo(name, tagPages))
+	}
+	return tags
+}
+
+// newTagInfo describes a tag for the tags index and breadcrumbs
+func newTagInfo(tag string, tagPages map[string][]*content.Page) templates.TagInfo {
+	return templates.TagInfo{
+		Name:  tag,
+		Label: tag[strings.LastIndex(tag, "/")+1:],
+		Count: len(tagPages[tag]),
+		Depth: strings.Count(tag, "/"),
+	}
+}
+
+// tagPageData returns the template data for a tag page, including its
+// parent tags for breadcrumbs and its direct child tags
+func tagPageData(tag string, pages []*content.Page, tagPages map[string][]*content.Page, siteData templates.SiteData) templates.TagPageData {
+	var parents, children []templates.TagInfo
+	for _, parent := range tagAncestors(tag) {
+		parents = append(parents, newTagInfo(parent, tagPages))
+	}
+	for name := range tagPages {
+		if strings.HasPrefix(name, tag+"/") && !strings.Contains(name[len(tag)+1:], "/") {
+			children = append(children, newTagInfo(name, tagPages))
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+
+	return templates.TagPageData{
+		Site:        siteData,
+		Tag:         tag,
+		Label:       tag[strings.LastIndex(tag, "/")+1:],
+		Parents:     parents,
+		Children:    children,
+		Pages:       pages,
+		CurrentPath: "/tags/" + tag + "/",
+	}
+}
+
 // getSectionPages returns pages in a section (falls back to linear scan if no index)
 func getSectionPages(section string, allPages []*content.Page) []*content.Page {
 	var result []*content.Page
@@ -1522,6 +2290,26 @@ func copyDir(src, dst string) error {
 	})
 }
 
+// renderOptions returns the markdown features enabled in the site config
+func (b *Builder) renderOptions(basePath string) content.RenderOptions {
+	callouts := make(map[string]content.CalloutType, len(b.cfg.Callouts))
+	for name, callout := range b.cfg.Callouts {
+		callouts[name] = content.CalloutType{Title: callout.Title, Icon: callout.Icon}
+	}
+
+	opts := content.RenderOptions{
+		Wikilinks:   b.cfg.Wikilinks,
+		Math:        b.cfg.Math,
+		BasePath:    basePath,
+		Callouts:    callouts,
+		Attachments: b.attachments,
+	}
+	if b.shortcodes != nil {
+		opts.Shortcodes = b.shortcodes
+	}
+	return opts
+}
+
 func encodeJSON(f *os.File, v interface{}) error {
 	encoder := json.NewEncoder(f)
 	encoder.SetIndent("", "  ")
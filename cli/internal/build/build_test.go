@@ -0,0 +1,150 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shivamx96/leafpress/cli/internal/config"
+	"github.com/shivamx96/leafpress/cli/internal/content"
+)
+
+// writeSite writes files (slash paths relative to the site root) into a temp
+// directory and changes into it, as the builder works from the current directory
+func writeSite(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+// buildSite builds the site in the current directory with cfg
+func buildSite(t *testing.T, cfg *config.Config) (*Builder, *Stats) {
+	t.Helper()
+	b := New(cfg, Options{})
+	stats, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	return b, stats
+}
+
+// readOutput returns a file from the output directory, failing if it's missing
+func readOutput(t *testing.T, b *Builder, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(b.outputDir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatalf("reading %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestRedirects(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"notes/garden.md": "---\ntitle: Garden\ntags: [plants]\naliases: [Yard]\nredirect_from: [old/garden, tags]\n---\nHello\n",
+		"other.md":        "---\ntitle: Other\naliases: [Garden Two]\n---\nBody\n",
+	})
+	b, stats := buildSite(t, config.Default())
+
+	stub := readOutput(t, b, "notes/yard/index.html")
+	if !strings.Contains(stub, "/notes/garden/") {
+		t.Errorf("alias stub doesn't point at the page:\n%s", stub)
+	}
+	readOutput(t, b, "old/garden/index.html")
+	readOutput(t, b, "garden-two/index.html")
+
+	// tags/index.html is the generated tag list and must survive
+	if tags := readOutput(t, b, "tags/index.html"); strings.Contains(tags, `http-equiv="refresh"`) {
+		t.Errorf("redirect overwrote the tag index:\n%s", tags)
+	}
+	if stats.WarningCount == 0 {
+		t.Error("expected a warning for the redirect conflicting with /tags/")
+	}
+
+	// Stubs are never written outside the output directory, even for
+	// entries that slipped past frontmatter validation
+	page := &content.Page{Slug: "escape", Permalink: "/escape/", SourcePath: "escape.md", RedirectFrom: []string{"../../escaped"}}
+	if _, err := b.writeRedirects(page, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "escaped")); err == nil {
+		t.Error("redirect stub written outside the output directory")
+	}
+}
+
+func TestRedirectFromRejectsDotSegments(t *testing.T) {
+	writeSite(t, map[string]string{
+		"note.md": "---\ntitle: Note\nredirect_from: [../../escaped]\n---\nBody\n",
+	})
+	_, err := New(config.Default(), Options{}).Build()
+	if err == nil || !strings.Contains(err.Error(), "invalid redirect_from") {
+		t.Errorf("Build() error = %v, want an invalid redirect_from error", err)
+	}
+}
+
+func TestIncrementalTagPages(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"note.md": "---\ntitle: Note\ntags: [Area/Sub]\n---\nBody\n",
+	})
+	b, _ := buildSite(t, config.Default())
+	readOutput(t, b, "tags/area/sub/index.html")
+
+	// The same tag spelled differently keeps its page
+	notePath := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(notePath, []byte("---\ntitle: Note\ntags: [area / sub]\n---\nBody\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.RebuildIncremental(notePath, ChangeModify); err != nil {
+		t.Fatal(err)
+	}
+	if page := readOutput(t, b, "tags/area/sub/index.html"); !strings.Contains(page, "/note/") {
+		t.Errorf("tag page lost the note:\n%s", page)
+	}
+
+	// Deleting the note removes its tag pages
+	if err := os.Remove(notePath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.RebuildIncremental(notePath, ChangeDelete); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"tags/area/sub/index.html", "tags/area/index.html"} {
+		if _, err := os.Stat(filepath.Join(b.outputDir, filepath.FromSlash(name))); err == nil {
+			t.Errorf("%s left behind after the note was deleted", name)
+		}
+	}
+}
+
+func TestIncrementalNewLayoutDir(t *testing.T) {
+	dir := writeSite(t, map[string]string{"note.md": "---\ntitle: Note\n---\nBody\n"})
+	b, _ := buildSite(t, config.Default())
+
+	// A layouts folder moved in with its files is reported as one new directory
+	partial := filepath.Join(dir, "layouts", "partials", "footer.html")
+	if err := os.MkdirAll(filepath.Dir(partial), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(partial, []byte(`<footer class="custom-footer">Mine</footer>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := b.RebuildIncremental(filepath.Join(dir, "layouts"), ChangeCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.FullRebuild {
+		t.Error("a new layouts directory didn't trigger a full rebuild")
+	}
+	if page := readOutput(t, b, "note/index.html"); !strings.Contains(page, "custom-footer") {
+		t.Errorf("note doesn't use the new footer partial:\n%s", page)
+	}
+}
@@ -0,0 +1,391 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shivamx96/leafpress/cli/internal/content"
+	"github.com/shivamx96/leafpress/cli/internal/linkcheck"
+)
+
+var (
+	// refTagRegex matches tags whose href, src or srcset point at other files
+	refTagRegex = regexp.MustCompile(`<(a|img|source|video|audio|iframe)\s[^>]*>`)
+	// attrRegex matches a double-quoted attribute
+	attrRegex = regexp.MustCompile(`([a-zA-Z][\w-]*)="([^"]*)"`)
+	// idAttrRegex matches id attributes, the targets of #fragments
+	idAttrRegex = regexp.MustCompile(`\sid="([^"]*)"`)
+)
+
+// CheckIssue is a problem found by Check or CheckExternal, located in a source file
+type CheckIssue struct {
+	Source   string `json:"source"`           // File relative to the site root
+	Line     int    `json:"line,omitempty"`   // Line in the source file (0 if unknown)
+	Kind     string `json:"kind"`             // e.g., "broken link", "missing anchor", "math"
+	Severity string `json:"severity"`         // "error", or "warning" for problems that don't break the site
+	Target   string `json:"target,omitempty"` // URL or link that failed, if any
+	Message  string `json:"message"`
+}
+
+func (i CheckIssue) String() string {
+	location := i.Source
+	if i.Line > 0 {
+		location += fmt.Sprintf(":%d", i.Line)
+	}
+	if i.Severity == "warning" {
+		return location + ": warning: " + i.Message
+	}
+	return location + ": " + i.Message
+}
+
+// pageRef is a URL found in a page's rendered HTML
+type pageRef struct {
+	tag   string // a, img, ...
+	value string // URL as written (unescaped)
+	src   string // For srcset candidates, the src of the same tag
+}
+
+// Check validates the site produced by the last Build. Every internal href,
+// image src and #fragment in the generated HTML files must point at a
+// generated file and an id in it; problems in page content are reported at
+// their line in the note, and problems from templates (tag pages, backlinks,
+// section indexes, ...) once, at the generated file. Render warnings (broken
+// wiki-links, embeds, queries, math) are reported too, except links to
+// unpublished notes, along with frontmatter schema violations.
+func (b *Builder) Check() ([]CheckIssue, error) {
+	if b.pages == nil {
+		return nil, fmt.Errorf("nothing to check: build the site first")
+	}
+
+	files, err := b.outputFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list output files: %w", err)
+	}
+	ids := make(map[string]map[string]bool) // Output file -> ids (loaded lazily)
+	basePath := extractBasePath(b.cfg.BaseURL)
+
+	var issues []CheckIssue
+	for _, page := range b.pages {
+		for i, w := range page.Warnings {
+			if strings.HasPrefix(w, "unpublished ") {
+				continue
+			}
+			kind, _, _ := strings.Cut(w, ":")
+			issue := CheckIssue{Source: page.SourcePath, Kind: kind, Severity: "error", Message: w}
+			if i < len(page.WarningLines) {
+				issue.Line = page.WarningLines[i]
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	for _, v := range validateSchemas(b.pages, b.cfg.Schema) {
+		data, _ := os.ReadFile(filepath.Join(b.rootDir, v.Source))
+		issues = append(issues, CheckIssue{
+			Source:   v.Source,
+			Line:     content.FrontmatterFieldLine(string(data), v.Field),
+			Kind:     "schema",
+			Severity: "error",
+			Message:  "schema: " + v.Message,
+		})
+	}
+
+	// Problems outside page content are reported once, by kind and target
+	reported := make(map[string]bool)
+
+	// Nav links come from the config rather than a page
+	for _, nav := range b.cfg.Nav {
+		ref := pageRef{tag: "a", value: basePath + nav.Path}
+		if kind, target := b.checkRef("/", ref, basePath, files, ids); kind != "" {
+			reported[kind+" "+target] = true
+			issues = append(issues, CheckIssue{
+				Source:   "leafpress.json",
+				Kind:     kind,
+				Severity: "error",
+				Target:   target,
+				Message:  fmt.Sprintf("%s: nav item %q links to %s", kind, nav.Label, nav.Path),
+			})
+		}
+	}
+
+	pagesByOutput := make(map[string]*content.Page, len(b.pages))
+	for _, page := range b.pages {
+		pagesByOutput[filepath.ToSlash(page.OutputPath)] = page
+	}
+	var htmlFiles []string
+	for file := range files {
+		if strings.HasSuffix(file, ".html") {
+			htmlFiles = append(htmlFiles, file)
+		}
+	}
+	sort.Strings(htmlFiles)
+
+	for _, file := range htmlFiles {
+		data, err := os.ReadFile(filepath.Join(b.outputDir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		page := pagesByOutput[file]
+		locator := newRefLocator(page)
+		for _, ref := range extractRefs(string(data)) {
+			line, inContent := locator.locate(ref)
+			kind, target := b.checkRef(outputURL(file), ref, basePath, files, ids)
+			if kind == "" {
+				continue
+			}
+			issue := CheckIssue{Kind: kind, Severity: "error", Target: target, Message: kind + ": " + ref.value}
+			if inContent {
+				issue.Source, issue.Line = page.SourcePath, line
+			} else {
+				if reported[kind+" "+target] {
+					continue
+				}
+				reported[kind+" "+target] = true
+				issue.Source = path.Join(b.cfg.OutputDir, file)
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	sortIssues(issues)
+	return issues, nil
+}
+
+// refLocator tells a page's content refs from template refs in its generated
+// file, and finds the source lines of the content ones
+type refLocator struct {
+	page      *content.Page
+	remaining map[string]int // Content refs not seen yet, by URL
+	seen      map[string]int // Content refs seen, by URL
+}
+
+// newRefLocator returns a locator for the generated file of page (nil for other files)
+func newRefLocator(page *content.Page) *refLocator {
+	if page == nil {
+		return nil
+	}
+	l := &refLocator{page: page, remaining: make(map[string]int), seen: make(map[string]int)}
+	for _, ref := range extractRefs(page.HTMLContent) {
+		l.remaining[ref.value]++
+	}
+	return l
+}
+
+// locate reports whether ref comes from the page content and if so its line
+// (0 if unknown). Refs must be passed in the order they appear in the file.
+func (l *refLocator) locate(ref pageRef) (int, bool) {
+	if l == nil || l.remaining[ref.value] == 0 {
+		return 0, false
+	}
+	l.remaining[ref.value]--
+
+	lines := l.page.RefLines[ref.value]
+	if len(lines) == 0 && ref.src != "" {
+		lines = l.page.RefLines[ref.src] // srcset variants come from the image's src
+	}
+	n := l.seen[ref.value]
+	l.seen[ref.value]++
+	if len(lines) == 0 {
+		return 0, true
+	}
+	if n >= len(lines) {
+		n = len(lines) - 1
+	}
+	return lines[n], true
+}
+
+// outputURL returns the site URL of a generated file: the directory for
+// index.html files, otherwise the file itself
+func outputURL(file string) string {
+	if file == "index.html" || strings.HasSuffix(file, "/index.html") {
+		return "/" + strings.TrimSuffix(file, "index.html")
+	}
+	return "/" + file
+}
+
+// CheckExternal checks the http(s) links in the pages of the last Build.
+// Dead links are errors; rate-limited, redirected and slow links are warnings.
+func (b *Builder) CheckExternal(ctx context.Context, checker *linkcheck.Checker) []CheckIssue {
+	linksByPage := make(map[*content.Page][]string)
+	var all []string
+	for _, page := range b.pages {
+		for _, ref := range extractRefs(page.HTMLContent) {
+			if ref.tag == "a" && (strings.HasPrefix(ref.value, "http://") || strings.HasPrefix(ref.value, "https://")) {
+				linksByPage[page] = append(linksByPage[page], ref.value)
+				all = append(all, ref.value)
+			}
+		}
+	}
+	results := checker.Check(ctx, all)
+
+	var issues []CheckIssue
+	for page, links := range linksByPage {
+		seen := make(map[string]bool)
+		for _, link := range links {
+			if seen[link] {
+				continue
+			}
+			seen[link] = true
+
+			result := results[link]
+			issue := CheckIssue{Source: page.SourcePath, Target: link, Severity: "warning"}
+			if lines := page.RefLines[link]; len(lines) > 0 {
+				issue.Line = lines[0]
+			}
+			switch {
+			case result.Dead():
+				issue.Kind, issue.Severity = "dead link", "error"
+				if result.Error != "" {
+					issue.Message = fmt.Sprintf("dead link: %s (%s)", link, result.Error)
+				} else {
+					issue.Message = fmt.Sprintf("dead link: %s (HTTP %d)", link, result.Status)
+				}
+			case result.RateLimited():
+				issue.Kind = "rate-limited link"
+				issue.Message = fmt.Sprintf("rate-limited link: %s (HTTP 429, not cached so the next check retries it)", link)
+			case result.Redirected():
+				issue.Kind = "redirected link"
+				issue.Message = fmt.Sprintf("redirected link: %s -> %s", link, result.FinalURL)
+			case checker.Slow(result):
+				issue.Kind = "slow link"
+				issue.Message = fmt.Sprintf("slow link: %s (%s)", link, result.Duration.Round(time.Millisecond))
+			default:
+				continue
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	sortIssues(issues)
+	return issues
+}
+
+// sortIssues orders issues by file and line
+func sortIssues(issues []CheckIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Source != issues[j].Source {
+			return issues[i].Source < issues[j].Source
+		}
+		return issues[i].Line < issues[j].Line
+	})
+}
+
+// checkRef checks one URL from the page at pageURL, returning the kind of
+// problem and the resolved target, or an empty kind if the URL is fine or external
+func (b *Builder) checkRef(pageURL string, ref pageRef, basePath string, files map[string]bool, ids map[string]map[string]bool) (kind, target string) {
+	notFound := "broken link"
+	if ref.tag != "a" {
+		notFound = "missing image"
+		if ref.tag != "img" && ref.tag != "source" {
+			notFound = "missing file"
+		}
+	}
+
+	u, err := url.Parse(ref.value)
+	if err != nil {
+		return "invalid URL", ref.value
+	}
+	if u.Scheme != "" || u.Host != "" || ref.value == "" {
+		return "", "" // External, mailto:, data: etc.
+	}
+
+	// Resolve relative URLs against the page's own URL
+	resolved := (&url.URL{Path: basePath + pageURL}).ResolveReference(u)
+	if !strings.HasPrefix(resolved.Path, basePath+"/") {
+		return notFound, resolved.Path
+	}
+	relPath := strings.TrimPrefix(resolved.Path, basePath+"/")
+
+	file := ""
+	for _, candidate := range []string{relPath, path.Join(relPath, "index.html")} {
+		if candidate != "" && candidate != "." && files[candidate] {
+			file = candidate
+			break
+		}
+	}
+	if file == "" {
+		return notFound, resolved.Path
+	}
+
+	if resolved.Fragment == "" || !strings.HasSuffix(file, ".html") {
+		return "", ""
+	}
+	if ids[file] == nil {
+		ids[file] = b.fileIDs(file)
+	}
+	if !ids[file][resolved.Fragment] {
+		return "missing anchor", resolved.Path + "#" + resolved.Fragment
+	}
+	return "", ""
+}
+
+// outputFiles lists the generated files, relative to the output directory with forward slashes
+func (b *Builder) outputFiles() (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(b.outputDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(b.outputDir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+// fileIDs returns the id attributes in a generated HTML file
+func (b *Builder) fileIDs(relPath string) map[string]bool {
+	ids := make(map[string]bool)
+	data, err := os.ReadFile(filepath.Join(b.outputDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return ids
+	}
+	for _, match := range idAttrRegex.FindAllStringSubmatch(string(data), -1) {
+		ids[html.UnescapeString(match[1])] = true
+	}
+	return ids
+}
+
+// extractRefs returns the URLs in rendered HTML that should point at site files.
+// Wiki-links are skipped: they're checked while rendering and show up as warnings.
+func extractRefs(htmlContent string) []pageRef {
+	var refs []pageRef
+	for _, match := range refTagRegex.FindAllStringSubmatch(htmlContent, -1) {
+		tag := match[1]
+		attrs := make(map[string]string)
+		for _, attr := range attrRegex.FindAllStringSubmatch(match[0], -1) {
+			attrs[strings.ToLower(attr[1])] = html.UnescapeString(attr[2])
+		}
+
+		if tag == "a" {
+			if href, ok := attrs["href"]; ok && !strings.Contains(attrs["class"], "lp-wikilink") {
+				refs = append(refs, pageRef{tag: tag, value: href})
+			}
+			continue
+		}
+		src, hasSrc := attrs["src"]
+		if hasSrc {
+			refs = append(refs, pageRef{tag: tag, value: src})
+		}
+		// srcset is a list of "url width" candidates
+		for _, candidate := range strings.Split(attrs["srcset"], ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				refs = append(refs, pageRef{tag: tag, value: fields[0], src: src})
+			}
+		}
+	}
+	return refs
+}
@@ -0,0 +1,248 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fontFormats maps font file extensions to @font-face formats, best first
+var fontFormats = []struct{ ext, format string }{
+	{".woff2", "woff2"},
+	{".woff", "woff"},
+	{".ttf", "truetype"},
+	{".otf", "opentype"},
+}
+
+// fontWeights maps weight names used in font file names to CSS weights
+var fontWeights = map[string]string{
+	"thin": "100", "hairline": "100",
+	"extralight": "200", "ultralight": "200",
+	"light":   "300",
+	"regular": "400", "normal": "400", "book": "400",
+	"medium":   "500",
+	"semibold": "600", "demibold": "600",
+	"bold":      "700",
+	"extrabold": "800", "ultrabold": "800",
+	"black": "900", "heavy": "900",
+	"variable": "100 900", "variablefont": "100 900",
+}
+
+// fontVersionRegex matches the version segment of google-webfonts-helper
+// names (e.g., "v13" in inter-v13-latin-700.woff2); what follows is the subset
+var fontVersionRegex = regexp.MustCompile(`^v\d+$`)
+
+// fontFace is one @font-face rule: a family, weight and style, in one or more formats
+type fontFace struct {
+	Family string
+	Weight string     // e.g., "400", or "100 900" for variable fonts
+	Style  string     // "normal" or "italic"
+	Files  []fontFile // Best format first
+}
+
+// fontFile is a font file from a fonts/ directory
+type fontFile struct {
+	Name string // File name, also used in the output fonts/
+	Path string // Source path
+}
+
+// findFonts collects the font files in the fonts/ directories of the theme and
+// the site (site files win), grouped into faces. Families that match a
+// configured font take its name, so the CSS variables pick them up.
+func (b *Builder) findFonts() ([]fontFace, []string, error) {
+	files := make(map[string]string) // File name -> source path
+	for _, dir := range b.overrideDirs() {
+		entries, err := os.ReadDir(filepath.Join(dir, "fonts"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read fonts: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && fontFormat(entry.Name()) != "" {
+				files[entry.Name()] = filepath.Join(dir, "fonts", entry.Name())
+			}
+		}
+	}
+
+	configured := []string{b.cfg.Theme.FontHeading, b.cfg.Theme.FontBody, b.cfg.Theme.FontMono}
+	faces := make(map[string]*fontFace)
+	for name, path := range files {
+		family, weight, style := parseFontFileName(name)
+		for _, font := range configured {
+			if fontKey(font) == fontKey(family) {
+				family = font
+			}
+		}
+		key := family + "|" + weight + "|" + style
+		if faces[key] == nil {
+			faces[key] = &fontFace{Family: family, Weight: weight, Style: style}
+		}
+		faces[key].Files = append(faces[key].Files, fontFile{Name: name, Path: path})
+	}
+
+	result := make([]fontFace, 0, len(faces))
+	found := make(map[string]bool)
+	for _, face := range faces {
+		sort.Slice(face.Files, func(i, j int) bool {
+			return fontFormatRank(face.Files[i].Name) < fontFormatRank(face.Files[j].Name)
+		})
+		result = append(result, *face)
+		found[face.Family] = true
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Family != result[j].Family {
+			return result[i].Family < result[j].Family
+		}
+		if result[i].Weight != result[j].Weight {
+			return result[i].Weight < result[j].Weight
+		}
+		return result[i].Style < result[j].Style
+	})
+
+	var warnings []string
+	for _, font := range configured {
+		if !found[font] {
+			warnings = append(warnings, fmt.Sprintf("no font files for %q in fonts/, using fallback fonts", font))
+			found[font] = true // Warn once per family
+		}
+	}
+	return result, warnings, nil
+}
+
+// copyFonts copies the files of faces to fonts/ in the output
+func (b *Builder) copyFonts(faces []fontFace) error {
+	for _, face := range faces {
+		for _, file := range face.Files {
+			data, err := os.ReadFile(file.Path)
+			if err != nil {
+				return err
+			}
+			if err := b.writeAsset("fonts/"+file.Name, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fontFaceCSS returns the @font-face rules for faces. assetPath maps a font
+// file (fonts/x.woff2) to its output path; URLs are relative to style.css,
+// which sits at the root of the output.
+func fontFaceCSS(faces []fontFace, assetPath func(string) string) string {
+	var sb strings.Builder
+	for _, face := range faces {
+		sources := make([]string, len(face.Files))
+		for i, file := range face.Files {
+			sources[i] = fmt.Sprintf("url(\"%s\") format(\"%s\")", assetPath("fonts/"+file.Name), fontFormat(file.Name))
+		}
+		fmt.Fprintf(&sb, "@font-face {\n  font-family: %q;\n  src: %s;\n  font-weight: %s;\n  font-style: %s;\n  font-display: swap;\n}\n\n",
+			face.Family, strings.Join(sources, ",\n       "), face.Weight, face.Style)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// fontPreloads returns the files worth preloading: the regular upright
+// woff2 of the heading and body fonts, which nearly every page uses
+func fontPreloads(faces []fontFace, families ...string) []string {
+	var preloads []string
+	seen := make(map[string]bool)
+	for _, family := range families {
+		for _, face := range faces {
+			best := face.Files[0].Name
+			if face.Family != family || face.Style != "normal" || seen[best] {
+				continue
+			}
+			if (face.Weight == "400" || strings.Contains(face.Weight, " ")) && fontFormat(best) == "woff2" {
+				seen[best] = true
+				preloads = append(preloads, "fonts/"+best)
+			}
+		}
+	}
+	return preloads
+}
+
+// parseFontFileName guesses a font's family, weight and style from its file
+// name, e.g. "Inter-BoldItalic.woff2", "inter-v13-latin-700.woff2" or
+// "Inter[wght].woff2". Names without a weight are taken as regular.
+func parseFontFileName(name string) (family, weight, style string) {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	weight, style = "400", "normal"
+
+	// Variable fonts: Inter[wght].woff2, Inter-Italic[wght].woff2
+	if i := strings.Index(stem, "["); i >= 0 {
+		stem, weight = stem[:i], "100 900"
+	}
+
+	tokens := strings.FieldsFunc(stem, func(r rune) bool { return r == '-' || r == '_' || r == ' ' })
+	if len(tokens) > 1 {
+		last := strings.ToLower(tokens[len(tokens)-1])
+		desc, italic := strings.CutSuffix(last, "italic")
+		if !italic {
+			desc, italic = strings.CutSuffix(last, "oblique")
+		}
+		w, known := fontWeights[desc]
+		if n, err := strconv.Atoi(desc); err == nil && n >= 100 && n <= 900 {
+			w, known = desc, true
+		}
+		if desc == "" && italic {
+			w, known = weight, true
+		}
+		if known {
+			tokens = tokens[:len(tokens)-1]
+			if weight != "100 900" {
+				weight = w
+			}
+			if italic {
+				style = "italic"
+			}
+		}
+	}
+
+	// Drop google-webfonts-helper's version and subset: inter-v13-latin-700
+	for i, token := range tokens {
+		if i > 0 && fontVersionRegex.MatchString(token) {
+			tokens = tokens[:i]
+			break
+		}
+	}
+	return strings.Join(tokens, " "), weight, style
+}
+
+// fontKey normalizes a family name for matching: "Crimson Pro" = "crimson-pro" = "CrimsonPro"
+func fontKey(family string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(family) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// fontFormat returns the @font-face format of a font file, or "" if it isn't one
+func fontFormat(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, f := range fontFormats {
+		if f.ext == ext {
+			return f.format
+		}
+	}
+	return ""
+}
+
+// fontFormatRank orders font files by format, best first
+func fontFormatRank(name string) int {
+	ext := strings.ToLower(filepath.Ext(name))
+	for i, f := range fontFormats {
+		if f.ext == ext {
+			return i
+		}
+	}
+	return len(fontFormats)
+}
@@ -0,0 +1,441 @@
+package build
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register GIF for DecodeConfig
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shivamx96/leafpress/cli/internal/config"
+	"github.com/shivamx96/leafpress/cli/internal/content"
+)
+
+// imageCacheDir holds resized variants and dimensions, keyed by content hash
+const imageCacheDir = ".leafpress/images"
+
+var (
+	// imgTagRegex matches <img> tags in rendered HTML (captures attributes)
+	imgTagRegex = regexp.MustCompile(`<img\s+([^>]*?)\s*/?>`)
+	// imgAttrRegex matches name="value" attributes
+	imgAttrRegex = regexp.MustCompile(`([\w-]+)="([^"]*)"`)
+)
+
+// imageInfo describes a processed image
+type imageInfo struct {
+	Width    int            `json:"width"`
+	Height   int            `json:"height"`
+	Variants []imageVariant `json:"-"`
+	Warnings []string       `json:"-"` // Variants left out, and why
+}
+
+// imageVariant is a resized copy of an image
+type imageVariant struct {
+	URL   string
+	Width int
+}
+
+// imageEntry processes a single source image at most once per build
+type imageEntry struct {
+	once sync.Once
+	info *imageInfo
+	err  error
+}
+
+// imageProcessor adds srcset, sizes and intrinsic dimensions to local images,
+// generating resized variants next to the original in the output
+type imageProcessor struct {
+	rootDir    string
+	outputDir  string
+	basePath   string
+	sourceDirs []string // Directories whose files are published (theme and site)
+	cfg        config.ImagesConfig
+
+	mu          sync.Mutex
+	entries     map[string]*imageEntry // Source path -> result
+	attachments map[string]bool        // Vault attachments that pages embed, which are published
+}
+
+// newImageProcessor creates an image processor for one build
+func newImageProcessor(rootDir, outputDir, basePath string, sourceDirs []string, cfg config.ImagesConfig) *imageProcessor {
+	return &imageProcessor{
+		rootDir:     rootDir,
+		outputDir:   outputDir,
+		basePath:    basePath,
+		sourceDirs:  sourceDirs,
+		cfg:         cfg,
+		entries:     make(map[string]*imageEntry),
+		attachments: make(map[string]bool),
+	}
+}
+
+// publish records that a vault attachment is published, so its variants can be too
+func (p *imageProcessor) publish(relPath string) {
+	p.mu.Lock()
+	p.attachments[filepath.ToSlash(relPath)] = true
+	p.mu.Unlock()
+}
+
+// published reports whether the build publishes the file at relPath: files
+// under static/ are copied as a whole, vault attachments only when embedded
+func (p *imageProcessor) published(relPath string) bool {
+	if strings.HasPrefix(relPath, "static/") {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.attachments[relPath]
+}
+
+// processImages rewrites the images in the pages' HTML in parallel, returning warnings
+func (b *Builder) processImages(pages []*content.Page) []string {
+	if b.images == nil || len(pages) == 0 {
+		return nil
+	}
+
+	// Embedded attachments are copied to the output, so their variants can be published
+	if b.attachments != nil {
+		for _, page := range pages {
+			for _, target := range content.ExtractFileEmbeds(page.RawContent) {
+				if attachment := b.attachments.Resolve(target).Attachment; attachment != nil && !attachment.Static {
+					b.images.publish(attachment.SourcePath)
+				}
+			}
+		}
+	}
+
+	numWorkers := runtime.NumCPU()
+	if numWorkers > len(pages) {
+		numWorkers = len(pages)
+	}
+
+	pageChan := make(chan *content.Page, len(pages))
+	var wg sync.WaitGroup
+	var warningsMu sync.Mutex
+	var allWarnings []string
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pageChan {
+				rewritten, warnings := b.images.rewrite(page.HTMLContent)
+				page.HTMLContent = rewritten
+				if len(warnings) > 0 {
+					warningsMu.Lock()
+					for _, w := range warnings {
+						allWarnings = append(allWarnings, w+" in "+page.SourcePath)
+					}
+					warningsMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, page := range pages {
+		pageChan <- page
+	}
+	close(pageChan)
+	wg.Wait()
+
+	return allWarnings
+}
+
+// rewrite adds srcset, sizes, width and height to the local images in html
+func (p *imageProcessor) rewrite(htmlContent string) (string, []string) {
+	var warnings []string
+	result := imgTagRegex.ReplaceAllStringFunc(htmlContent, func(tag string) string {
+		attrs := imgTagRegex.FindStringSubmatch(tag)[1]
+		values := make(map[string]string)
+		for _, attr := range imgAttrRegex.FindAllStringSubmatch(attrs, -1) {
+			values[attr[1]] = attr[2]
+		}
+		// Leave images the author already sized alone
+		if values["srcset"] != "" || values["width"] != "" || values["height"] != "" {
+			return tag
+		}
+
+		src := html.UnescapeString(values["src"])
+		relPath, ok := p.sourcePath(src)
+		if !ok {
+			return tag
+		}
+
+		info, err := p.process(relPath, src)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("image %s: %v", relPath, err))
+			return tag
+		}
+		for _, w := range info.Warnings {
+			warnings = append(warnings, fmt.Sprintf("image %s: %s", relPath, w))
+		}
+
+		extra := fmt.Sprintf(` width="%d" height="%d"`, info.Width, info.Height)
+		if len(info.Variants) > 0 {
+			var srcset []string
+			for _, v := range info.Variants {
+				srcset = append(srcset, fmt.Sprintf("%s %dw", v.URL, v.Width))
+			}
+			srcset = append(srcset, fmt.Sprintf("%s %dw", src, info.Width))
+			extra += fmt.Sprintf(` srcset="%s" sizes="%s"`, html.EscapeString(strings.Join(srcset, ", ")), html.EscapeString(p.cfg.Sizes))
+		}
+		if strings.HasSuffix(tag, "/>") {
+			return "<img " + attrs + extra + " />"
+		}
+		return "<img " + attrs + extra + ">"
+	})
+	return result, warnings
+}
+
+// sourcePath maps an image URL to a published PNG, JPEG or GIF file in the site root
+func (p *imageProcessor) sourcePath(src string) (string, bool) {
+	if src == "" || strings.Contains(src, "://") || strings.HasPrefix(src, "//") || strings.HasPrefix(src, "data:") {
+		return "", false
+	}
+	if !strings.HasPrefix(src, "/") {
+		return "", false // Relative URLs depend on the page they appear on
+	}
+	if p.basePath != "" {
+		if !strings.HasPrefix(src, p.basePath+"/") {
+			return "", false
+		}
+		src = strings.TrimPrefix(src, p.basePath)
+	}
+	if idx := strings.IndexAny(src, "?#"); idx >= 0 {
+		src = src[:idx]
+	}
+	unescaped, err := url.PathUnescape(src)
+	if err != nil {
+		return "", false
+	}
+
+	relPath := strings.TrimPrefix(path.Clean(unescaped), "/")
+	switch strings.ToLower(path.Ext(relPath)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+	default:
+		return "", false
+	}
+	if relPath == "" || strings.HasPrefix(relPath, "..") {
+		return "", false
+	}
+	// Files in ignored or private folders aren't published, and neither are their variants
+	if !p.published(relPath) {
+		return "", false
+	}
+	if _, err := os.Stat(filepath.Join(p.rootDir, filepath.FromSlash(relPath))); err != nil {
+		return "", false
+	}
+	return relPath, true
+}
+
+// process generates the variants of an image once per build
+func (p *imageProcessor) process(relPath, src string) (*imageInfo, error) {
+	p.mu.Lock()
+	entry, ok := p.entries[relPath]
+	if !ok {
+		entry = &imageEntry{}
+		p.entries[relPath] = entry
+	}
+	p.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.info, entry.err = p.generate(relPath, src)
+	})
+	return entry.info, entry.err
+}
+
+// refresh regenerates the variants of an image that changed on disk, if a page uses it
+func (p *imageProcessor) refresh(relPath string) error {
+	if p == nil {
+		return nil
+	}
+	relPath = filepath.ToSlash(relPath)
+	p.mu.Lock()
+	_, used := p.entries[relPath]
+	delete(p.entries, relPath)
+	p.mu.Unlock()
+	if !used {
+		return nil
+	}
+
+	src := p.basePath + (&url.URL{Path: "/" + relPath}).EscapedPath()
+	_, err := p.process(relPath, src)
+	return err
+}
+
+// generate reads the image's dimensions and writes its resized variants to the
+// output, reusing cached results for content it has seen before
+func (p *imageProcessor) generate(relPath, src string) (*imageInfo, error) {
+	data, err := os.ReadFile(filepath.Join(p.rootDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(path.Ext(relPath))
+	hash := sha256.New()
+	hash.Write(data)
+	fmt.Fprintf(hash, "q%d", p.cfg.Quality)
+	key := hex.EncodeToString(hash.Sum(nil)[:16])
+	cacheDir := filepath.Join(p.rootDir, imageCacheDir)
+
+	// Dimensions are cached next to the variants
+	info := &imageInfo{}
+	metaPath := filepath.Join(cacheDir, key+".json")
+	if meta, err := os.ReadFile(metaPath); err != nil || json.Unmarshal(meta, info) != nil || info.Width == 0 {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dimensions: %w", err)
+		}
+		info.Width, info.Height = cfg.Width, cfg.Height
+		meta, _ := json.Marshal(info)
+		if err := writeFile(metaPath, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	// Animated GIFs would lose their frames, so they only get dimensions
+	if ext == ".gif" {
+		return info, nil
+	}
+
+	widths := append([]int(nil), p.cfg.Widths...)
+	sort.Ints(widths)
+
+	var decoded image.Image
+	for i, width := range widths {
+		if width >= info.Width || (i > 0 && width == widths[i-1]) {
+			continue
+		}
+
+		name := fmt.Sprintf("%s-%dw%s", strings.TrimSuffix(path.Base(relPath), path.Ext(relPath)), width, path.Ext(relPath))
+		variantRel := path.Join(path.Dir(relPath), name)
+		if p.isSourceFile(variantRel) {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("%dw variant skipped, %s already exists", width, variantRel))
+			continue
+		}
+		cachePath := filepath.Join(cacheDir, fmt.Sprintf("%s-%dw%s", key, width, ext))
+
+		variant, err := os.ReadFile(cachePath)
+		if err != nil {
+			if decoded == nil {
+				if decoded, _, err = image.Decode(bytes.NewReader(data)); err != nil {
+					return nil, fmt.Errorf("failed to decode: %w", err)
+				}
+			}
+			if variant, err = encodeImage(resizeImage(decoded, width), ext, p.cfg.Quality); err != nil {
+				return nil, fmt.Errorf("failed to encode %dw variant: %w", width, err)
+			}
+			if err := writeFile(cachePath, variant); err != nil {
+				return nil, err
+			}
+		}
+
+		outPath := filepath.Join(p.outputDir, filepath.FromSlash(variantRel))
+		if err := writeFile(outPath, variant); err != nil {
+			return nil, err
+		}
+
+		info.Variants = append(info.Variants, imageVariant{
+			URL:   path.Join(path.Dir(src), url.PathEscape(name)),
+			Width: width,
+		})
+	}
+
+	return info, nil
+}
+
+// isSourceFile reports whether the site or theme has a file at relPath, which
+// a variant of the same name would overwrite in the output
+func (p *imageProcessor) isSourceFile(relPath string) bool {
+	for _, dir := range p.sourceDirs {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(relPath))); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFile writes data to path, creating parent directories
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// encodeImage encodes img in the format matching ext
+func encodeImage(img image.Image, ext string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if ext == ".png" {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	return buf.Bytes(), err
+}
+
+// resizeImage scales img down to width, keeping its aspect ratio. Each output
+// pixel averages the source pixels it covers (a box filter), which is enough
+// for downscaling and avoids pulling in an imaging library.
+func resizeImage(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	height := srcH * width / srcW
+	if height < 1 {
+		height = 1
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, (y+1)*srcH/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, (x+1)*srcW/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					px := row[sx*4 : sx*4+4]
+					r += uint32(px[0])
+					g += uint32(px[1])
+					b += uint32(px[2])
+					a += uint32(px[3])
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
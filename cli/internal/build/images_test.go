@@ -0,0 +1,145 @@
+package build
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shivamx96/leafpress/cli/internal/config"
+)
+
+// testPNG returns a PNG image of the given size
+func testPNG(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func imagesConfig(widths ...int) *config.Config {
+	cfg := config.Default()
+	cfg.Images.Widths = widths
+	return cfg
+}
+
+func TestImagesOffByDefault(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"note.md":                 "![Photo](/static/images/photo.png)\n",
+		"static/images/photo.png": testPNG(t, 1000, 500),
+	})
+	b, _ := buildSite(t, config.Default())
+
+	page := readOutput(t, b, "note/index.html")
+	if strings.Contains(page, "srcset") || strings.Contains(page, `width="1000"`) {
+		t.Errorf("image processed without widths configured:\n%s", page)
+	}
+	if _, err := os.Stat(filepath.Join(dir, imageCacheDir)); err == nil {
+		t.Errorf("%s created without widths configured", imageCacheDir)
+	}
+}
+
+func TestImageVariants(t *testing.T) {
+	writeSite(t, map[string]string{
+		"note.md":                 "![Photo](/static/images/photo.png)\n\n![[diagram.png]]\n",
+		"static/images/photo.png": testPNG(t, 1000, 500),
+		"notes/diagram.png":       testPNG(t, 800, 800),
+	})
+	b, stats := buildSite(t, imagesConfig(600, 1200))
+
+	page := readOutput(t, b, "note/index.html")
+	for _, want := range []string{
+		`width="1000" height="500"`,
+		`srcset="/static/images/photo-600w.png 600w, /static/images/photo.png 1000w"`,
+		`srcset="/notes/diagram-600w.png 600w, /notes/diagram.png 800w"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page is missing %s:\n%s", want, page)
+		}
+	}
+	if stats.WarningCount != 0 {
+		t.Errorf("WarningCount = %d, want 0", stats.WarningCount)
+	}
+
+	variant, err := png.DecodeConfig(strings.NewReader(readOutput(t, b, "static/images/photo-600w.png")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variant.Width != 600 || variant.Height != 300 {
+		t.Errorf("variant is %dx%d, want 600x300", variant.Width, variant.Height)
+	}
+	readOutput(t, b, "notes/diagram-600w.png")
+}
+
+func TestImagesOnlyPublished(t *testing.T) {
+	writeSite(t, map[string]string{
+		"note.md":              "![Secret](/private/secret.png)\n\n![Vault](/notes/unembedded.png)\n",
+		"private/secret.png":   testPNG(t, 1000, 500),
+		"notes/unembedded.png": testPNG(t, 1000, 500),
+	})
+	cfg := imagesConfig(600)
+	cfg.Ignore = []string{"private"}
+	b, _ := buildSite(t, cfg)
+
+	for _, name := range []string{"private/secret-600w.png", "notes/unembedded-600w.png"} {
+		if _, err := os.Stat(filepath.Join(b.outputDir, filepath.FromSlash(name))); err == nil {
+			t.Errorf("%s published for an image the build doesn't publish", name)
+		}
+	}
+	if page := readOutput(t, b, "note/index.html"); strings.Contains(page, "srcset") {
+		t.Errorf("unpublished image got a srcset:\n%s", page)
+	}
+}
+
+func TestImageVariantCollision(t *testing.T) {
+	mine := testPNG(t, 10, 10)
+	writeSite(t, map[string]string{
+		"note.md":                      "![Photo](/static/images/photo.png)\n",
+		"static/images/photo.png":      testPNG(t, 1000, 500),
+		"static/images/photo-600w.png": mine,
+	})
+	b, stats := buildSite(t, imagesConfig(600))
+
+	if got := readOutput(t, b, "static/images/photo-600w.png"); got != mine {
+		t.Error("variant overwrote a site file with the same name")
+	}
+	if page := readOutput(t, b, "note/index.html"); strings.Contains(page, "photo-600w.png 600w") {
+		t.Errorf("srcset lists the colliding variant:\n%s", page)
+	}
+	if stats.WarningCount != 1 {
+		t.Errorf("WarningCount = %d, want 1", stats.WarningCount)
+	}
+}
+
+func TestIncrementalImageWarnings(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"note.md":                      "Text\n",
+		"static/images/photo.png":      testPNG(t, 1000, 500),
+		"static/images/photo-600w.png": testPNG(t, 10, 10),
+	})
+	b, _ := buildSite(t, imagesConfig(600))
+
+	notePath := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(notePath, []byte("![Photo](/static/images/photo.png)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := b.RebuildIncremental(notePath, ChangeModify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.WarningCount != 1 {
+		t.Errorf("WarningCount = %d, want 1", stats.WarningCount)
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	resized := resizeImage(image.NewRGBA(image.Rect(0, 0, 1000, 3)), 600)
+	if got := resized.Bounds().Size(); got != image.Pt(600, 1) {
+		t.Errorf("resized to %v, want (600,1)", got)
+	}
+}
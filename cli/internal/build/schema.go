@@ -0,0 +1,165 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shivamx96/leafpress/cli/internal/config"
+	"github.com/shivamx96/leafpress/cli/internal/content"
+)
+
+// SchemaViolation is a frontmatter field that breaks a folder's schema
+type SchemaViolation struct {
+	Source  string // File relative to the site root
+	Field   string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return v.Source + ": " + v.Message
+}
+
+// validateSchemas checks every page against the schemas of the folders it's in.
+// A page in notes/ideas/ is checked against "/", "notes" and "notes/ideas".
+func validateSchemas(pages []*content.Page, schemas map[string]config.FolderSchema) []SchemaViolation {
+	if len(schemas) == 0 {
+		return nil
+	}
+
+	// Apply schemas in a stable order, and compile each pattern once
+	folders := make([]string, 0, len(schemas))
+	for folder := range schemas {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+	patterns := make(map[string]*regexp.Regexp)
+	for _, schema := range schemas {
+		for _, field := range schema.Fields {
+			if field.Pattern != "" && patterns[field.Pattern] == nil {
+				if re, err := regexp.Compile(field.Pattern); err == nil {
+					patterns[field.Pattern] = re
+				}
+			}
+		}
+	}
+
+	var violations []SchemaViolation
+	for _, page := range pages {
+		for _, folder := range folders {
+			if !inFolder(page.SourcePath, folder) {
+				continue
+			}
+			schema := schemas[folder]
+
+			for _, field := range schema.Required {
+				if isEmptyParam(page.Param(field)) {
+					violations = append(violations, SchemaViolation{
+						Source:  page.SourcePath,
+						Field:   field,
+						Message: fmt.Sprintf("missing required field %q", field),
+					})
+				}
+			}
+
+			names := make([]string, 0, len(schema.Fields))
+			for name := range schema.Fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				value := page.Param(name)
+				if isEmptyParam(value) {
+					continue
+				}
+				field := schema.Fields[name]
+				if msg := checkField(value, field, patterns[field.Pattern]); msg != "" {
+					violations = append(violations, SchemaViolation{
+						Source:  page.SourcePath,
+						Field:   name,
+						Message: name + " " + msg,
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Source < violations[j].Source
+	})
+	return violations
+}
+
+// inFolder reports whether a source path is inside a schema folder ("" or "/" = everywhere)
+func inFolder(sourcePath, folder string) bool {
+	folder = strings.Trim(folder, "/")
+	return folder == "" || strings.HasPrefix(filepath.ToSlash(sourcePath), folder+"/")
+}
+
+// isEmptyParam reports whether a frontmatter value counts as not set
+func isEmptyParam(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// checkField returns what's wrong with a field value, or "" if it's valid
+func checkField(value any, field config.FieldSchema, pattern *regexp.Regexp) string {
+	if field.Type == "list" {
+		items, ok := value.([]any)
+		if !ok {
+			return "must be a list"
+		}
+		for _, item := range items {
+			if msg := checkScalar(item, field, pattern); msg != "" {
+				return "item " + msg
+			}
+		}
+		return ""
+	}
+	return checkScalar(value, field, pattern)
+}
+
+// checkScalar checks a single value against a field's type, values and pattern
+func checkScalar(value any, field config.FieldSchema, pattern *regexp.Regexp) string {
+	switch value.(type) {
+	case []any:
+		return "must be a single value, not a list"
+	case map[string]any:
+		return "must be a single value, not a table"
+	}
+
+	// Dates come from YAML as time.Time, and as strings from TOML and JSON
+	s := fmt.Sprint(value)
+	if t, ok := value.(time.Time); ok {
+		s = t.Format("2006-01-02")
+		if t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 {
+			s = t.Format(time.RFC3339)
+		}
+	}
+
+	if field.Type == "date" {
+		if _, ok := value.(time.Time); !ok {
+			if _, err := content.ParseDate(s); err != nil {
+				return fmt.Sprintf("must be a date (e.g., 2006-01-02), got %q", s)
+			}
+		}
+	}
+	if len(field.Values) > 0 && !slices.Contains(field.Values, s) {
+		return fmt.Sprintf("must be one of %s, got %q", strings.Join(field.Values, ", "), s)
+	}
+	if pattern != nil && !pattern.MatchString(s) {
+		return fmt.Sprintf("must match %s, got %q", field.Pattern, s)
+	}
+	return ""
+}
@@ -9,17 +9,24 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var includeDrafts bool
+var (
+	includeDrafts bool
+	buildStrict   bool
+)
 
 func buildCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "build",
 		Short: "Build the static site",
-		Long:  `Generates static site into _site/ directory.`,
-		RunE:  runBuild,
+		Long: `Generates static site into _site/ directory.
+
+Frontmatter that breaks the schema in leafpress.json is listed after the
+build. With --strict, the build fails instead.`,
+		RunE: runBuild,
 	}
 
 	cmd.Flags().BoolVarP(&includeDrafts, "drafts", "d", false, "include draft pages")
+	cmd.Flags().BoolVar(&buildStrict, "strict", false, "fail on frontmatter schema violations")
 
 	return cmd
 }
@@ -37,6 +44,7 @@ func runBuild(cmd *cobra.Command, args []string) error {
 	builder := build.New(cfg, build.Options{
 		IncludeDrafts: includeDrafts,
 		Verbose:       isVerbose(),
+		Strict:        buildStrict,
 	})
 
 	// Run build
@@ -52,5 +60,19 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Warnings: %d\n", stats.WarningCount)
 	}
 
+	if len(stats.UnpublishedLinks) > 0 {
+		fmt.Printf("Links to unpublished notes: %d (shown as plain text)\n", len(stats.UnpublishedLinks))
+		for _, link := range stats.UnpublishedLinks {
+			fmt.Printf("  %s\n", link)
+		}
+	}
+
+	if len(stats.SchemaViolations) > 0 {
+		fmt.Printf("Schema violations: %d\n", len(stats.SchemaViolations))
+		for _, v := range stats.SchemaViolations {
+			fmt.Printf("  %s\n", v)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/shivamx96/leafpress/cli/internal/build"
+	"github.com/shivamx96/leafpress/cli/internal/config"
+	"github.com/shivamx96/leafpress/cli/internal/linkcheck"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkFormat      string
+	checkExternal    bool
+	checkTimeout     time.Duration
+	checkConcurrency int
+	checkPerHost     int
+	checkSlow        time.Duration
+	checkCacheTTL    time.Duration
+)
+
+func checkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check links and content for problems",
+		Long: `Builds the site into a temporary directory (leaving _site/ alone) and checks it:
+every internal link, image and #anchor must point at a generated page, file
+or heading, and notes must render without warnings.
+
+With --external, http(s) links are requested too. Dead links are problems;
+rate-limited (HTTP 429), redirected and slow links are reported as warnings. Results are cached in
+.leafpress/linkcheck.json so unchanged links aren't requested on every run.
+
+Exits with a non-zero status if problems are found, for use in CI.
+
+Examples:
+  leafpress check                  # Print problems as file:line: message
+  leafpress check --format json    # Machine-readable output
+  leafpress check --external       # Also check links to other sites`,
+		RunE: runCheck,
+	}
+
+	cmd.Flags().BoolVarP(&includeDrafts, "drafts", "d", false, "include draft pages")
+	cmd.Flags().StringVar(&checkFormat, "format", "text", "output format (text|json)")
+	cmd.Flags().BoolVar(&checkExternal, "external", false, "also check external links")
+	cmd.Flags().DurationVar(&checkTimeout, "timeout", 10*time.Second, "timeout per external link")
+	cmd.Flags().IntVar(&checkConcurrency, "concurrency", 8, "external links checked at once")
+	cmd.Flags().IntVar(&checkPerHost, "per-host", 2, "external links checked at once per host")
+	cmd.Flags().DurationVar(&checkSlow, "slow", 3*time.Second, "report external links slower than this")
+	cmd.Flags().DurationVar(&checkCacheTTL, "cache-ttl", 24*time.Hour, "reuse external link results for this long (0 disables the cache)")
+
+	return cmd
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	if checkFormat != "text" && checkFormat != "json" {
+		return fmt.Errorf("unknown format %q (must be text or json)", checkFormat)
+	}
+
+	// Load config
+	cfg, err := config.Load(getConfigPath())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Build into a throwaway directory
+	tmpDir, err := os.MkdirTemp("", "leafpress-check-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	builder := build.New(cfg, build.Options{
+		IncludeDrafts: includeDrafts,
+		Verbose:       isVerbose() && checkFormat == "text",
+		OutputDir:     tmpDir,
+	})
+	stats, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+
+	issues, err := builder.Check()
+	if err != nil {
+		return fmt.Errorf("check failed: %w", err)
+	}
+
+	if checkExternal {
+		opts := linkcheck.Options{
+			Concurrency: checkConcurrency,
+			PerHost:     checkPerHost,
+			Timeout:     checkTimeout,
+			Slow:        checkSlow,
+			CacheTTL:    checkCacheTTL,
+		}
+		if checkCacheTTL > 0 {
+			opts.CacheFile = linkcheck.DefaultCacheFile
+		}
+		checker := linkcheck.New(opts)
+		issues = append(issues, builder.CheckExternal(cmd.Context(), checker)...)
+		if err := checker.SaveCache(); err != nil && checkFormat == "text" {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Severity == "error" {
+			errorCount++
+		}
+	}
+
+	if checkFormat == "json" {
+		report := struct {
+			Pages  int                `json:"pages"`
+			Issues []build.CheckIssue `json:"issues"`
+		}{Pages: stats.PageCount, Issues: issues}
+		if report.Issues == nil {
+			report.Issues = []build.CheckIssue{}
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		if errorCount == 0 {
+			fmt.Printf("Checked %d pages, no problems found\n", stats.PageCount)
+		}
+	}
+
+	if errorCount > 0 {
+		// Don't print usage: the command was used correctly
+		cmd.SilenceUsage = true
+		return fmt.Errorf("found %d problems in %d pages", errorCount, stats.PageCount)
+	}
+	return nil
+}
@@ -29,6 +29,7 @@ Your garden folder IS the product. leafpress is invisible infrastructure.`,
 	// Add subcommands
 	rootCmd.AddCommand(initCmd())
 	rootCmd.AddCommand(buildCmd())
+	rootCmd.AddCommand(checkCmd())
 	rootCmd.AddCommand(serveCmd())
 	rootCmd.AddCommand(newCmd())
 	rootCmd.AddCommand(deployCmd())
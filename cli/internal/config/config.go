@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -17,23 +18,54 @@ type Background struct {
 
 // Config represents the leafpress.json configuration
 type Config struct {
-	Title       string       `json:"title"`
-	Description string       `json:"description"` // Site-wide meta description
-	Author      string       `json:"author"`
-	BaseURL     string       `json:"baseURL"`
-	Image       string       `json:"image"` // Default OG image path (e.g., "/og-image.png")
-	OutputDir   string       `json:"outputDir"`
-	Port        int          `json:"port"`
-	Nav         []NavItem    `json:"nav"`
-	Theme       Theme        `json:"theme"`
-	Graph       bool         `json:"graph"`
-	Search      bool         `json:"search"`
-	TOC         bool         `json:"toc"`
-	Backlinks   bool         `json:"backlinks"`
-	Wikilinks   bool         `json:"wikilinks"`
-	Ignore      []string     `json:"ignore"`
-	HeadExtra   string       `json:"headExtra"` // Custom HTML to inject in <head>
-	Deploy      DeployConfig `json:"deploy"`    // Deployment configuration
+	Title       string                   `json:"title"`
+	Description string                   `json:"description"` // Site-wide meta description
+	Author      string                   `json:"author"`
+	BaseURL     string                   `json:"baseURL"`
+	Image       string                   `json:"image"` // Default OG image path (e.g., "/og-image.png")
+	OutputDir   string                   `json:"outputDir"`
+	Port        int                      `json:"port"`
+	Nav         []NavItem                `json:"nav"`
+	Theme       Theme                    `json:"theme"`
+	Graph       bool                     `json:"graph"`
+	Search      bool                     `json:"search"`
+	TOC         bool                     `json:"toc"`
+	Backlinks   bool                     `json:"backlinks"`
+	Wikilinks   bool                     `json:"wikilinks"`
+	Math        bool                     `json:"math"`       // Render $TeX$ math to MathML at build time
+	Callouts    map[string]CalloutConfig `json:"callouts"`   // Custom callout types, keyed by type name
+	Images      ImagesConfig             `json:"images"`     // Responsive image variants
+	MetaParams  []MetaParam              `json:"metaParams"` // Frontmatter params shown in the page header
+	Ignore      []string                 `json:"ignore"`
+	Slugs       string                   `json:"slugs"`     // "preserve" keeps file names in URLs as written, "normalize" makes them URL-safe
+	Dates       string                   `json:"dates"`     // Fallback for notes without dates: "file" (modification time) or "git" (commit history)
+	HeadExtra   string                   `json:"headExtra"` // Custom HTML to inject in <head>
+	Deploy      DeployConfig             `json:"deploy"`    // Deployment configuration
+
+	// Add content hashes to asset file names (style.3fa2c1d4.css), so hosts
+	// can cache them forever
+	Fingerprint bool `json:"fingerprint"`
+
+	// Publishing: "all" publishes every non-draft note, "optIn" only notes
+	// with publish: true or inside PublishFolders
+	PublishMode    string   `json:"publishMode"`
+	PublishFolders []string `json:"publishFolders"` // Folders published as a whole in optIn mode
+
+	// Frontmatter rules, keyed by folder ("/" for every note)
+	Schema map[string]FolderSchema `json:"schema"`
+}
+
+// FolderSchema lists the frontmatter rules for the notes in a folder
+type FolderSchema struct {
+	Required []string               `json:"required"` // Fields every note must set
+	Fields   map[string]FieldSchema `json:"fields"`   // Rules for fields, when set
+}
+
+// FieldSchema constrains one frontmatter field
+type FieldSchema struct {
+	Type    string   `json:"type"`    // "string", "date", "enum", or "list" (empty = any)
+	Values  []string `json:"values"`  // Allowed values (of each item, for lists)
+	Pattern string   `json:"pattern"` // Regular expression values must match (each item, for lists)
 }
 
 // DeployConfig holds deployment settings
@@ -42,6 +74,26 @@ type DeployConfig struct {
 	Settings map[string]string `json:"settings"` // Provider-specific settings
 }
 
+// CalloutConfig defines a custom callout type (> [!type])
+type CalloutConfig struct {
+	Title string `json:"title"` // Default title (defaults to the capitalized type name)
+	Icon  string `json:"icon"`  // Icon shown before the title (e.g., an emoji)
+	Color string `json:"color"` // Hex color for the border, background and title
+}
+
+// ImagesConfig controls the resized variants generated for local images
+type ImagesConfig struct {
+	Widths  []int  `json:"widths"`  // Variant widths in pixels (empty = images are left alone)
+	Sizes   string `json:"sizes"`   // sizes attribute that goes with srcset
+	Quality int    `json:"quality"` // JPEG quality (1-100)
+}
+
+// MetaParam shows a custom frontmatter field in the page header
+type MetaParam struct {
+	Param string `json:"param"` // Frontmatter field name (e.g., "status")
+	Label string `json:"label"` // Text shown before the value (optional)
+}
+
 // NavItem represents a navigation link
 type NavItem struct {
 	Label string `json:"label"`
@@ -50,9 +102,11 @@ type NavItem struct {
 
 // Theme represents theme configuration
 type Theme struct {
+	Name           string     `json:"name,omitempty"` // Theme directory: a name under themes/, or a path
 	FontHeading    string     `json:"fontHeading"`
 	FontBody       string     `json:"fontBody"`
 	FontMono       string     `json:"fontMono"`
+	FontSource     string     `json:"fontSource"` // "google" or "local" (files in fonts/)
 	Accent         string     `json:"accent"`
 	Background     Background `json:"-"`              // Custom unmarshaling
 	NavStyle       string     `json:"navStyle"`       // "base", "sticky", or "glassy"
@@ -61,6 +115,13 @@ type Theme struct {
 
 // UnmarshalJSON implements custom JSON unmarshaling for Theme
 func (t *Theme) UnmarshalJSON(data []byte) error {
+	// "theme": "name" picks a theme and keeps the other settings
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		t.Name = name
+		return nil
+	}
+
 	// Create a temporary struct to avoid recursion
 	type Alias Theme
 	aux := &struct {
@@ -103,6 +164,20 @@ func (t *Theme) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Dir returns the theme's directory: themes/<name> for a plain name, or the
+// name itself as a path relative to rootDir. Returns "" if no theme is set.
+func (t Theme) Dir(rootDir string) string {
+	switch {
+	case t.Name == "":
+		return ""
+	case filepath.IsAbs(t.Name):
+		return t.Name
+	case strings.ContainsAny(t.Name, `/\`):
+		return filepath.Join(rootDir, t.Name)
+	}
+	return filepath.Join(rootDir, "themes", t.Name)
+}
+
 // validateBackground checks if a background value is valid
 func validateBackground(bg string) error {
 	// Check for common CSS background patterns
@@ -146,6 +221,15 @@ func validateBackground(bg string) error {
 	return fmt.Errorf("invalid CSS background value: %s (must be a hex color, rgb/rgba, gradient, or color keyword)", bg)
 }
 
+// themeDefaults is what a theme's theme.json can set: how sites look, not
+// where they're built or how they're published. Its fields point into the
+// config being loaded, so the theme's values go over the built-in defaults.
+type themeDefaults struct {
+	Theme      *Theme                    `json:"theme"`
+	Callouts   *map[string]CalloutConfig `json:"callouts"`
+	MetaParams *[]MetaParam              `json:"metaParams"`
+}
+
 // Default returns a Config with default values
 func Default() *Config {
 	return &Config{
@@ -158,6 +242,7 @@ func Default() *Config {
 			FontHeading:    "Crimson Pro",
 			FontBody:       "Inter",
 			FontMono:       "JetBrains Mono",
+			FontSource:     "google",
 			Accent:         "#50ac00",
 			NavStyle:       "base",
 			NavActiveStyle: "base",
@@ -167,6 +252,13 @@ func Default() *Config {
 		TOC:       true,
 		Backlinks: true,
 		Wikilinks: true,
+		Images: ImagesConfig{
+			Sizes:   "(max-width: 600px) 100vw, 600px",
+			Quality: 80,
+		},
+		Slugs:       "preserve",
+		Dates:       "file",
+		PublishMode: "all",
 	}
 }
 
@@ -187,6 +279,30 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	// A theme's theme.json holds defaults, which the site's config overrides
+	if cfg.Theme.Name != "" {
+		themeDir := cfg.Theme.Dir(filepath.Dir(path))
+		if info, err := os.Stat(themeDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("theme '%s' not found (looked in %s)", cfg.Theme.Name, themeDir)
+		}
+		themeData, err := os.ReadFile(filepath.Join(themeDir, "theme.json"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read theme.json: %w", err)
+		}
+		if err == nil {
+			cfg = Default()
+			defaults := themeDefaults{Theme: &cfg.Theme, Callouts: &cfg.Callouts, MetaParams: &cfg.MetaParams}
+			decoder := json.NewDecoder(bytes.NewReader(themeData))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&defaults); err != nil {
+				return nil, fmt.Errorf("failed to parse theme.json: %w (a theme can set theme, callouts and metaParams)", err)
+			}
+			if err := json.Unmarshal(data, cfg); err != nil {
+				return nil, fmt.Errorf("failed to parse config: %w", err)
+			}
+		}
+	}
+
 	// Apply defaults for missing values
 	if cfg.OutputDir == "" {
 		cfg.OutputDir = "_site"
@@ -203,6 +319,9 @@ func Load(path string) (*Config, error) {
 	if cfg.Theme.FontMono == "" {
 		cfg.Theme.FontMono = "JetBrains Mono"
 	}
+	if cfg.Theme.FontSource == "" {
+		cfg.Theme.FontSource = "google"
+	}
 	if cfg.Theme.Accent == "" {
 		cfg.Theme.Accent = "#50ac00"
 	}
@@ -212,6 +331,21 @@ func Load(path string) (*Config, error) {
 	if cfg.Theme.NavActiveStyle == "" {
 		cfg.Theme.NavActiveStyle = "base"
 	}
+	if cfg.Images.Sizes == "" {
+		cfg.Images.Sizes = "(max-width: 600px) 100vw, 600px"
+	}
+	if cfg.Images.Quality == 0 {
+		cfg.Images.Quality = 80
+	}
+	if cfg.Slugs == "" {
+		cfg.Slugs = "preserve"
+	}
+	if cfg.Dates == "" {
+		cfg.Dates = "file"
+	}
+	if cfg.PublishMode == "" {
+		cfg.PublishMode = "all"
+	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -268,6 +402,78 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("accent color must be a valid hex color (e.g., #50ac00), got %s", c.Theme.Accent)
 	}
 
+	// Validate custom callout types
+	calloutNameRegex := regexp.MustCompile(`^[\w-]+$`)
+	for name, callout := range c.Callouts {
+		if !calloutNameRegex.MatchString(name) {
+			return fmt.Errorf("callout type must contain only letters, digits, hyphens and underscores, got '%s'", name)
+		}
+		if callout.Color != "" && !hexColorRegex.MatchString(callout.Color) {
+			return fmt.Errorf("callout '%s' color must be a valid hex color (e.g., #50ac00), got %s", name, callout.Color)
+		}
+	}
+
+	// Validate image settings
+	for _, width := range c.Images.Widths {
+		if width < 1 {
+			return fmt.Errorf("image widths must be positive, got %d", width)
+		}
+	}
+	if c.Images.Quality < 1 || c.Images.Quality > 100 {
+		return fmt.Errorf("image quality must be between 1 and 100, got %d", c.Images.Quality)
+	}
+
+	// Validate header params
+	for i, mp := range c.MetaParams {
+		if strings.TrimSpace(mp.Param) == "" {
+			return fmt.Errorf("metaParams item %d has empty param", i)
+		}
+	}
+
+	// Validate slug style
+	if c.Slugs != "preserve" && c.Slugs != "normalize" {
+		return fmt.Errorf("slugs must be 'preserve' or 'normalize', got '%s'", c.Slugs)
+	}
+
+	// Validate date source
+	if c.Dates != "file" && c.Dates != "git" {
+		return fmt.Errorf("dates must be 'file' or 'git', got '%s'", c.Dates)
+	}
+
+	// Validate publish mode
+	if c.PublishMode != "all" && c.PublishMode != "optIn" {
+		return fmt.Errorf("publishMode must be 'all' or 'optIn', got '%s'", c.PublishMode)
+	}
+	for i, folder := range c.PublishFolders {
+		if strings.Trim(folder, "/") == "" {
+			return fmt.Errorf("publishFolders item %d is empty", i)
+		}
+	}
+
+	// Validate frontmatter schemas
+	validFieldTypes := map[string]bool{"": true, "string": true, "date": true, "enum": true, "list": true}
+	for folder, schema := range c.Schema {
+		if strings.Contains(folder, "..") {
+			return fmt.Errorf("schema folder cannot contain '..', got '%s'", folder)
+		}
+		for i, field := range schema.Required {
+			if strings.TrimSpace(field) == "" {
+				return fmt.Errorf("schema '%s' required item %d is empty", folder, i)
+			}
+		}
+		for name, field := range schema.Fields {
+			if !validFieldTypes[field.Type] {
+				return fmt.Errorf("schema '%s' field '%s' type must be 'string', 'date', 'enum', or 'list', got '%s'", folder, name, field.Type)
+			}
+			if field.Type == "enum" && len(field.Values) == 0 {
+				return fmt.Errorf("schema '%s' field '%s' is an enum without values", folder, name)
+			}
+			if _, err := regexp.Compile(field.Pattern); err != nil {
+				return fmt.Errorf("schema '%s' field '%s' has an invalid pattern: %w", folder, name, err)
+			}
+		}
+	}
+
 	// Validate background values (basic check for common patterns)
 	if c.Theme.Background.Light != "" {
 		if err := validateBackground(c.Theme.Background.Light); err != nil {
@@ -280,6 +486,11 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate fontSource
+	if c.Theme.FontSource != "google" && c.Theme.FontSource != "local" {
+		return fmt.Errorf("fontSource must be 'google' or 'local', got '%s'", c.Theme.FontSource)
+	}
+
 	// Validate navStyle
 	validNavStyles := map[string]bool{"base": true, "sticky": true, "glassy": true}
 	if !validNavStyles[c.Theme.NavStyle] {
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files (slash paths) into a temp directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadThemeDefaults(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"leafpress.json": `{"title": "Site", "theme": {"name": "forest", "accent": "#123456"}, "callouts": {"tip": {"icon": "💡"}}}`,
+		"themes/forest/theme.json": `{
+			"theme": {"name": "other", "fontHeading": "Lora", "accent": "#2f855a", "navStyle": "sticky"},
+			"callouts": {"recipe": {"title": "Recipe", "icon": "🍳"}},
+			"metaParams": [{"param": "status"}]
+		}`,
+	})
+	cfg, err := Load(filepath.Join(dir, "leafpress.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	theme := cfg.Theme
+	if theme.Name != "forest" || theme.FontHeading != "Lora" || theme.NavStyle != "sticky" || theme.FontBody != "Inter" {
+		t.Errorf("theme = %+v, want forest's settings over the defaults", theme)
+	}
+	if theme.Accent != "#123456" {
+		t.Errorf("accent = %q, the site's setting should win", theme.Accent)
+	}
+	if cfg.Callouts["recipe"].Icon != "🍳" || cfg.Callouts["tip"].Icon != "💡" {
+		t.Errorf("callouts = %v, want the theme's and the site's", cfg.Callouts)
+	}
+	if len(cfg.MetaParams) != 1 || cfg.MetaParams[0].Param != "status" {
+		t.Errorf("metaParams = %v", cfg.MetaParams)
+	}
+}
+
+func TestLoadThemeSiteSettings(t *testing.T) {
+	for _, field := range []string{`"outputDir": "../elsewhere"`, `"headExtra": "<script src=x></script>"`, `"deploy": {"provider": "netlify"}`} {
+		dir := writeFiles(t, map[string]string{
+			"leafpress.json":           `{"theme": "forest"}`,
+			"themes/forest/theme.json": "{" + field + "}",
+		})
+		_, err := Load(filepath.Join(dir, "leafpress.json"))
+		if err == nil || !strings.Contains(err.Error(), "theme.json") {
+			t.Errorf("theme.json with %s: error = %v, want it rejected", field, err)
+		}
+	}
+}
+
+func TestLoadMissingTheme(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"leafpress.json": `{"theme": "nowhere"}`})
+	if _, err := Load(filepath.Join(dir, "leafpress.json")); err == nil || !strings.Contains(err.Error(), "theme 'nowhere' not found") {
+		t.Errorf("error = %v, want the theme not found", err)
+	}
+}
@@ -0,0 +1,119 @@
+package content
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment is a non-markdown file in the vault (image, PDF, audio, ...)
+// that notes can embed with ![[file.png]]
+type Attachment struct {
+	SourcePath string // Relative path to the file (e.g., "notes/assets/photo.png")
+	Static     bool   // Lives under static/, which is copied to the output as a whole
+}
+
+// Permalink returns the URL path of the attachment in the built site
+func (a *Attachment) Permalink() string {
+	segments := strings.Split(filepath.ToSlash(a.SourcePath), "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(segment, " ", "%20")
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// AttachmentIndex resolves embed targets to attachments
+type AttachmentIndex struct {
+	attachments []*Attachment
+	pathMap     map[string]*Attachment   // Exact path (lowercase) -> attachment
+	nameMap     map[string][]*Attachment // Filename (lowercase) -> attachments (may have duplicates)
+}
+
+// NewAttachmentIndex creates an index over the given attachments
+func NewAttachmentIndex(attachments []*Attachment) *AttachmentIndex {
+	index := &AttachmentIndex{
+		attachments: attachments,
+		pathMap:     make(map[string]*Attachment),
+		nameMap:     make(map[string][]*Attachment),
+	}
+
+	for _, a := range attachments {
+		// Map by exact path (lowercase)
+		pathLower := strings.ToLower(filepath.ToSlash(a.SourcePath))
+		index.pathMap[pathLower] = a
+
+		// Map by filename (lowercase)
+		name := path.Base(pathLower)
+		index.nameMap[name] = append(index.nameMap[name], a)
+	}
+
+	return index
+}
+
+// Attachments returns every indexed attachment
+func (x *AttachmentIndex) Attachments() []*Attachment {
+	return x.attachments
+}
+
+// AttachmentResult represents the result of resolving an attachment embed
+type AttachmentResult struct {
+	Attachment *Attachment
+	Ambiguous  bool
+	Broken     bool
+}
+
+// Resolve resolves an embed target to an attachment by path, then by filename
+func (x *AttachmentIndex) Resolve(target string) AttachmentResult {
+	targetLower := strings.ToLower(strings.TrimPrefix(filepath.ToSlash(target), "/"))
+
+	// 1. Exact path match
+	if a, ok := x.pathMap[targetLower]; ok {
+		return AttachmentResult{Attachment: a}
+	}
+
+	// 2. Filename match anywhere; prefer vault files over static/ when a name is shared
+	if attachments, ok := x.nameMap[targetLower]; ok {
+		if len(attachments) == 1 {
+			return AttachmentResult{Attachment: attachments[0]}
+		}
+		var vault []*Attachment
+		for _, a := range attachments {
+			if !a.Static {
+				vault = append(vault, a)
+			}
+		}
+		switch len(vault) {
+		case 0:
+			return AttachmentResult{Attachment: attachments[0], Ambiguous: true}
+		case 1:
+			return AttachmentResult{Attachment: vault[0]}
+		default:
+			return AttachmentResult{Attachment: vault[0], Ambiguous: true}
+		}
+	}
+
+	// 3. Missing attachment
+	return AttachmentResult{Broken: true}
+}
+
+// EmbedNames reports whether an embed target names the file at relPath, by
+// path or by filename. Unlike Resolve, it works for files not in the index yet.
+func EmbedNames(target, relPath string) bool {
+	targetLower := strings.ToLower(strings.TrimPrefix(filepath.ToSlash(target), "/"))
+	pathLower := strings.ToLower(filepath.ToSlash(relPath))
+	return targetLower == pathLower || targetLower == path.Base(pathLower)
+}
+
+// ExtractFileEmbeds returns the targets of file embeds (![[photo.png]]) in content,
+// skipping note embeds
+func ExtractFileEmbeds(content string) []string {
+	var targets []string
+	for _, match := range embedRegex.FindAllStringSubmatch(content, -1) {
+		target := strings.TrimSpace(match[1])
+		if isNoteEmbed(target) {
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
@@ -0,0 +1,284 @@
+package content
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// calloutStartRegex matches > [!type], > [!type] title and the foldable
+// > [!type]- title (collapsed) and > [!type]+ title (expanded) forms
+var calloutStartRegex = regexp.MustCompile(`^>[ \t]*\[!([\w-]+)\]([+-]?)(?:[ \t]+(.*?))?[ \t]*\r?\n?$`)
+
+// CalloutType is the display title and icon of a callout type
+type CalloutType struct {
+	Title string
+	Icon  string
+}
+
+// calloutTypes maps built-in callout types to display title and icon
+var calloutTypes = map[string]CalloutType{
+	"note":      {"Note", "📝"},
+	"tip":       {"Tip", "💡"},
+	"hint":      {"Hint", "💡"},
+	"important": {"Important", "❗"},
+	"warning":   {"Warning", "⚠️"},
+	"caution":   {"Caution", "⚠️"},
+	"danger":    {"Danger", "🔴"},
+	"error":     {"Error", "🔴"},
+	"info":      {"Info", "ℹ️"},
+	"todo":      {"Todo", "☑️"},
+	"example":   {"Example", "📋"},
+	"quote":     {"Quote", "💬"},
+	"question":  {"Question", "❓"},
+	"faq":       {"FAQ", "❓"},
+	"success":   {"Success", "✅"},
+	"check":     {"Check", "✅"},
+	"done":      {"Done", "✅"},
+	"fail":      {"Fail", "❌"},
+	"failure":   {"Failure", "❌"},
+	"bug":       {"Bug", "🐛"},
+	"abstract":  {"Abstract", "📄"},
+	"summary":   {"Summary", "📄"},
+	"tldr":      {"TL;DR", "📄"},
+}
+
+// KindCallout is the NodeKind for callouts
+var KindCallout = ast.NewNodeKind("Callout")
+
+// Callout is an Obsidian-style callout block. Its first child is the
+// CalloutTitle; the remaining children are the callout content.
+type Callout struct {
+	ast.BaseBlock
+	CalloutType string // Lowercase type (note, warning, ...)
+	Icon        string
+	Title       string // Default title, used when no custom title is given
+	Foldable    bool   // [!type]- or [!type]+, rendered as <details>
+	Collapsed   bool   // [!type]- starts closed
+}
+
+// Kind implements ast.Node.Kind
+func (n *Callout) Kind() ast.NodeKind { return KindCallout }
+
+// Dump implements ast.Node.Dump
+func (n *Callout) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"CalloutType": n.CalloutType}, nil)
+}
+
+// KindCalloutTitle is the NodeKind for callout titles
+var KindCalloutTitle = ast.NewNodeKind("CalloutTitle")
+
+// CalloutTitle holds a callout's custom title as inline content
+type CalloutTitle struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind
+func (n *CalloutTitle) Kind() ast.NodeKind { return KindCalloutTitle }
+
+// Dump implements ast.Node.Dump
+func (n *CalloutTitle) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// calloutParser parses callouts
+// Input: > [!note] Optional title
+//
+//	> Content here
+type calloutParser struct {
+	types map[string]CalloutType // Built-in and user-defined callout types
+}
+
+func (p *calloutParser) Trigger() []byte {
+	return []byte{'>'}
+}
+
+func (p *calloutParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if w > 3 || pos >= len(line) {
+		return nil, parser.NoChildren
+	}
+	matches := calloutStartRegex.FindSubmatchIndex(line[pos:])
+	if matches == nil {
+		return nil, parser.NoChildren
+	}
+
+	calloutType := strings.ToLower(string(line[pos+matches[2] : pos+matches[3]]))
+	fold := string(line[pos+matches[4] : pos+matches[5]])
+
+	// Get callout info or use defaults
+	info, ok := p.types[calloutType]
+	if !ok {
+		info = CalloutType{Title: cases.Title(language.Und).String(calloutType), Icon: "📌"}
+	}
+
+	node := &Callout{
+		CalloutType: calloutType,
+		Icon:        info.Icon,
+		Title:       info.Title,
+		Foldable:    fold != "",
+		Collapsed:   fold == "-",
+	}
+
+	// A custom title is parsed as inline markdown
+	title := &CalloutTitle{}
+	if matches[6] >= 0 && matches[7] > matches[6] {
+		title.Lines().Append(text.NewSegment(segment.Start+pos+matches[6], segment.Start+pos+matches[7]))
+	}
+	node.AppendChild(node, title)
+
+	reader.AdvanceToEOL()
+	return node, parser.HasChildren
+}
+
+func (p *calloutParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if line == nil {
+		return parser.Close
+	}
+
+	// An empty line continues the callout if the next line resumes it
+	if util.IsBlank(line) {
+		next := reader.Source()[segment.Stop:]
+		if idx := bytes.IndexByte(next, '\n'); idx >= 0 {
+			next = next[:idx+1]
+		}
+		// Skip the markers of enclosing quotes and callouts to reach this callout's level
+		rest, ok := stripQuoteMarkers(next, quoteDepth(node.Parent()))
+		if ok && isQuoteLine(rest) && !isCalloutStart(rest) {
+			return parser.Continue | parser.HasChildren
+		}
+		return parser.Close
+	}
+
+	// A new callout ends this one
+	if isCalloutStart(line) {
+		return parser.Close
+	}
+
+	if advanceQuoteMarker(reader) {
+		return parser.Continue | parser.HasChildren
+	}
+	return parser.Close
+}
+
+func (p *calloutParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *calloutParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *calloutParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// isCalloutStart returns true if line opens a callout (> [!type])
+func isCalloutStart(line []byte) bool {
+	w, pos := util.IndentWidth(line, 0)
+	return w <= 3 && calloutStartRegex.Match(line[pos:])
+}
+
+// isQuoteLine returns true if line starts with a blockquote marker
+func isQuoteLine(line []byte) bool {
+	w, pos := util.IndentWidth(line, 0)
+	return w <= 3 && pos < len(line) && line[pos] == '>'
+}
+
+// quoteDepth counts the blockquotes and callouts enclosing node
+func quoteDepth(node ast.Node) int {
+	depth := 0
+	for n := node; n != nil; n = n.Parent() {
+		switch n.(type) {
+		case *ast.Blockquote, *Callout:
+			depth++
+		}
+	}
+	return depth
+}
+
+// stripQuoteMarkers removes n leading "> " markers (and any indentation) from
+// line, returning false if it has fewer
+func stripQuoteMarkers(line []byte, n int) ([]byte, bool) {
+	for i := 0; i < n; i++ {
+		line = bytes.TrimLeft(line, " \t")
+		if len(line) == 0 || line[0] != '>' {
+			return nil, false
+		}
+		line = line[1:]
+	}
+	return bytes.TrimLeft(line, " \t"), true
+}
+
+// advanceQuoteMarker skips a leading "> " the way goldmark's blockquote parser does
+func advanceQuoteMarker(reader text.Reader) bool {
+	line, _ := reader.PeekLine()
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if w > 3 || pos >= len(line) || line[pos] != '>' {
+		return false
+	}
+	pos++
+	if pos >= len(line) || line[pos] == '\n' {
+		reader.Advance(pos)
+		return true
+	}
+	reader.Advance(pos)
+	if line[pos] == ' ' || line[pos] == '\t' {
+		padding := 0
+		if line[pos] == '\t' {
+			padding = util.TabWidth(reader.LineOffset()) - 1
+		}
+		reader.AdvanceAndSetPadding(1, padding)
+	}
+	return true
+}
+
+// renderCallout renders a callout
+// Output: <div class="lp-callout lp-callout-note">...</div>
+// Foldable callouts render as <details class="lp-callout lp-callout-note">...</details>
+func renderCallout(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Callout)
+	tag := "div"
+	if n.Foldable {
+		tag = "details"
+	}
+	if entering {
+		w.WriteString("<" + tag + ` class="lp-callout lp-callout-`)
+		w.WriteString(n.CalloutType)
+		w.WriteString(`"`)
+		if n.Foldable && !n.Collapsed {
+			w.WriteString(` open`)
+		}
+		w.WriteString(">\n")
+	} else {
+		w.WriteString("</div>\n</" + tag + ">\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+// renderCalloutTitle renders the title bar and opens the content wrapper
+func renderCalloutTitle(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	callout := node.Parent().(*Callout)
+	tag := "div"
+	if callout.Foldable {
+		tag = "summary"
+	}
+	if entering {
+		w.WriteString("<" + tag + ` class="lp-callout-title"><span class="lp-callout-icon">`)
+		w.WriteString(callout.Icon)
+		w.WriteString(`</span> `)
+		if !node.HasChildren() {
+			w.WriteString(callout.Title)
+		}
+	} else {
+		w.WriteString("</" + tag + ">\n<div class=\"lp-callout-content\">\n")
+	}
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,66 @@
+package content
+
+import "strings"
+
+// StripComments removes Obsidian comments (%% private %%), inline or spanning
+// several lines, leaving code blocks and code spans untouched. An unclosed %%
+// hides the rest of the note, as it does in Obsidian.
+func StripComments(content string) string {
+	stripped, _ := stripComments(content)
+	return stripped
+}
+
+// stripComments is StripComments, also returning the line of content each
+// line of the result starts on
+func stripComments(content string) (string, lineMap) {
+	if !strings.Contains(content, "%%") {
+		return content, nil
+	}
+
+	var out lineWriter
+	out.sb.Grow(len(content))
+	inFence, inComment := false, false
+	for n, line := range strings.SplitAfter(content, "\n") {
+		if !inComment && isFenceLine(line) {
+			inFence = !inFence
+			out.write(line, n)
+			continue
+		}
+		if inFence {
+			out.write(line, n)
+			continue
+		}
+
+		for i := 0; i < len(line); {
+			if inComment {
+				end := strings.Index(line[i:], "%%")
+				if end < 0 {
+					break // Comment continues on the next line
+				}
+				i += end + 2
+				inComment = false
+				continue
+			}
+
+			switch {
+			case line[i] == '`':
+				// Copy code spans as-is
+				run := len(line[i:]) - len(strings.TrimLeft(line[i:], "`"))
+				span := run
+				if end := strings.Index(line[i+run:], line[i:i+run]); end >= 0 {
+					span += end + run
+				}
+				out.write(line[i:i+span], n)
+				i += span
+			case strings.HasPrefix(line[i:], "%%"):
+				inComment = true
+				i += 2
+			default:
+				out.write(line[i:i+1], n)
+				i++
+			}
+		}
+	}
+
+	return out.String(), out.lines
+}
@@ -0,0 +1,336 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// MaxEmbedDepth limits how deeply notes can transclude other notes
+const MaxEmbedDepth = 4
+
+// embedRegex matches ![[target]], ![[target#Heading]], ![[target#^block]] and ![[target|label]]
+var embedRegex = regexp.MustCompile(`!\[\[([^\]|]+?)(?:\|([^\]]+))?\]\]`)
+
+var (
+	// atxHeadingRegex matches markdown ATX headings (# Heading)
+	atxHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	// blockIDRegex matches an Obsidian block ID at the end of a line (text ^block-id)
+	blockIDRegex = regexp.MustCompile(`(?:^|\s)\^([A-Za-z0-9-]+)\s*$`)
+)
+
+// Embed represents a parsed note embed (![[note]])
+type Embed struct {
+	Target   string // Page part of the target (slug or filename)
+	Fragment string // Heading text or ^block-id (without the leading #)
+	Raw      string // Original raw text including brackets
+}
+
+// IsBlockRef returns true if the embed targets a block ID (![[note#^id]])
+func (e Embed) IsBlockRef() bool {
+	return strings.HasPrefix(e.Fragment, "^")
+}
+
+// ExtractEmbeds extracts note embeds from content, skipping image and other file embeds
+func ExtractEmbeds(content string) []Embed {
+	var embeds []Embed
+	for _, match := range embedRegex.FindAllStringSubmatch(content, -1) {
+		target := strings.TrimSpace(match[1])
+		if !isNoteEmbed(target) {
+			continue
+		}
+		page, fragment := splitFragment(target)
+		embeds = append(embeds, Embed{
+			Target:   page,
+			Fragment: fragment,
+			Raw:      match[0],
+		})
+	}
+	return embeds
+}
+
+// isNoteEmbed returns true if an embed target points at a note rather than a file
+func isNoteEmbed(target string) bool {
+	page, _ := splitFragment(target)
+	ext := strings.ToLower(filepath.Ext(page))
+	return ext == "" || ext == ".md"
+}
+
+// splitFragment splits "note#Heading" into "note" and "Heading"
+func splitFragment(target string) (string, string) {
+	if idx := strings.Index(target, "#"); idx >= 0 {
+		return strings.TrimSpace(target[:idx]), strings.TrimSpace(target[idx+1:])
+	}
+	return target, ""
+}
+
+// KindEmbed is the NodeKind for note embeds
+var KindEmbed = ast.NewNodeKind("Embed")
+
+// EmbedNode is a ![[note]] embed in the markdown AST. The embedded note is
+// rendered while parsing, so the node only holds the finished HTML.
+type EmbedNode struct {
+	ast.BaseInline
+	Embed Embed
+	HTML  string
+}
+
+// Kind implements ast.Node.Kind
+func (n *EmbedNode) Kind() ast.NodeKind { return KindEmbed }
+
+// Dump implements ast.Node.Dump
+func (n *EmbedNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Target": n.Embed.Target}, nil)
+}
+
+// renderEmbedNode writes the pre-rendered HTML of a note embed
+func renderEmbedNode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString(node.(*EmbedNode).HTML)
+		// Embeds lifted out of their paragraph stand on their own line
+		if _, inline := node.Parent().(*ast.Paragraph); !inline {
+			w.WriteByte('\n')
+		}
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// fileEmbed converts an Obsidian file embed (![[image.png|alt]]) to an image node,
+// resolving the file against the attachment index when there is one
+func (r *Renderer) fileEmbed(target, alt, raw string, warnings *[]string) ast.Node {
+	if alt == "" {
+		alt = target
+	}
+
+	// Without an index, images are expected in static/images (URL-encode spaces)
+	dest := "/static/images/" + strings.ReplaceAll(target, " ", "%20")
+	if r.attachments != nil {
+		resolved := r.attachments.Resolve(target)
+		if resolved.Broken {
+			*warnings = append(*warnings, "missing attachment: "+raw)
+			broken := &WikiLinkNode{Broken: true}
+			broken.AppendChild(broken, ast.NewString([]byte(raw[1:])))
+			return broken
+		}
+		if resolved.Ambiguous {
+			*warnings = append(*warnings, "ambiguous attachment: "+raw)
+		}
+		dest = r.basePath + resolved.Attachment.Permalink()
+	}
+
+	link := ast.NewLink()
+	link.Destination = []byte(dest)
+
+	image := ast.NewImage(link)
+	image.AppendChild(image, ast.NewString([]byte(alt)))
+	return image
+}
+
+// unwrapEmbeds lifts note embeds out of paragraphs that contain nothing else,
+// so the embed's block HTML isn't nested inside a <p>
+func unwrapEmbeds(paragraph *ast.Paragraph, source []byte) {
+	hasEmbed := false
+	for c := paragraph.FirstChild(); c != nil; c = c.NextSibling() {
+		switch n := c.(type) {
+		case *EmbedNode:
+			hasEmbed = true
+		case *ast.Text:
+			if len(bytes.TrimSpace(n.Segment.Value(source))) > 0 {
+				return
+			}
+		default:
+			return
+		}
+	}
+	if !hasEmbed {
+		return
+	}
+
+	parent := paragraph.Parent()
+	for c := paragraph.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		if _, ok := c.(*EmbedNode); ok {
+			parent.InsertBefore(parent, paragraph, c)
+		}
+		c = next
+	}
+	parent.RemoveChild(parent, paragraph)
+}
+
+// renderEmbed renders the HTML for a single note embed
+func (r *Renderer) renderEmbed(embed Embed, stack []*Page, warnings *[]string) string {
+	broken := `<span class="lp-broken-link">` + html.EscapeString(embed.Raw[1:]) + `</span>`
+	if r.resolver == nil {
+		return broken
+	}
+
+	resolved := r.resolver.Resolve(embed.Target)
+	if resolved.Broken {
+		// Never pull private notes into published ones, just name them
+		if r.resolver.IsUnpublished(embed.Target) {
+			var current *Page
+			if len(stack) > 0 {
+				current = stack[len(stack)-1]
+			}
+			*warnings = append(*warnings, unpublishedWarning("embed", embed.Raw, current))
+			return string(util.EscapeHTML([]byte(embed.Target)))
+		}
+		*warnings = append(*warnings, "broken embed: "+embed.Raw)
+		return broken
+	}
+	if resolved.Ambiguous {
+		*warnings = append(*warnings, "ambiguous embed: "+embed.Raw)
+	}
+	target := resolved.Page
+
+	// Detect cycles (A embeds B embeds A) and runaway nesting, reporting the
+	// note being expanded, which holds the offending embed
+	for _, p := range stack {
+		if p == target {
+			*warnings = append(*warnings, "embed cycle: "+embed.Raw+" in "+stack[len(stack)-1].SourcePath)
+			return `<div class="lp-embed lp-embed-error">Embed cycle: ` + html.EscapeString(target.Title) + `</div>`
+		}
+	}
+	if len(stack) >= MaxEmbedDepth {
+		*warnings = append(*warnings, "embed depth exceeded: "+embed.Raw+" in "+stack[len(stack)-1].SourcePath)
+		return `<div class="lp-embed lp-embed-error">Embed depth exceeded: ` + html.EscapeString(target.Title) + `</div>`
+	}
+
+	// Pick the part of the note to embed
+	source := target.RawContent
+	if embed.Fragment != "" {
+		var ok bool
+		if embed.IsBlockRef() {
+			source, ok = extractBlock(source, strings.TrimPrefix(embed.Fragment, "^"))
+		} else {
+			source, ok = extractSection(source, embed.Fragment)
+		}
+		if !ok {
+			*warnings = append(*warnings, missingFragmentWarning(embed.IsBlockRef())+": "+embed.Raw)
+			return broken
+		}
+	}
+
+	result := r.render(source, append(stack, target))
+	rendered := result.html
+	*warnings = append(*warnings, result.warnings...)
+
+	src := r.basePath + target.Permalink
+	href := src
+	if embed.Fragment != "" {
+		href += "#" + FragmentAnchor(embed.Fragment)
+	}
+
+	return fmt.Sprintf(
+		"<div class=\"lp-embed\" data-embed-src=\"%s\">\n<div class=\"lp-embed-title\"><a class=\"lp-wikilink\" href=\"%s\">%s</a></div>\n<div class=\"lp-embed-content\">\n%s\n</div>\n</div>",
+		html.EscapeString(src),
+		html.EscapeString(href),
+		html.EscapeString(target.Title),
+		rendered,
+	)
+}
+
+// extractSection returns the markdown under the heading matching text (compared by
+// HeadingID), up to the next heading of the same or higher level
+func extractSection(content, heading string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	want := HeadingID(heading)
+
+	start, level := -1, 0
+	inFence := false
+	for i, line := range lines {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		matches := atxHeadingRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		if start < 0 {
+			if HeadingID(headingText(matches[2])) == want {
+				start, level = i, len(matches[1])
+			}
+			continue
+		}
+		if len(matches[1]) <= level {
+			return strings.Join(lines[start:i], "\n"), true
+		}
+	}
+
+	if start < 0 {
+		return "", false
+	}
+	return strings.Join(lines[start:], "\n"), true
+}
+
+// extractBlock returns the paragraph or list item marked with ^id, without the marker
+func extractBlock(content, id string) (string, bool) {
+	lines := strings.Split(content, "\n")
+
+	inFence := false
+	for i, line := range lines {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		matches := blockIDRegex.FindStringSubmatch(line)
+		if matches == nil || matches[1] != id {
+			continue
+		}
+
+		// A marker on its own line refers to the block above it
+		if strings.TrimSpace(line) == "^"+id {
+			end := i
+			for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+				end--
+			}
+			start := end
+			for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+				start--
+			}
+			if start == end {
+				return "", false
+			}
+			return strings.Join(lines[start:end], "\n"), true
+		}
+
+		stripped := strings.TrimRight(strings.TrimSuffix(strings.TrimRight(line, " \t"), "^"+id), " \t")
+
+		// List items are embedded on their own
+		if listItemRegex.MatchString(line) {
+			return stripped, true
+		}
+
+		// Otherwise embed the whole paragraph containing the marker
+		start := i
+		for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+			start--
+		}
+		block := append(append([]string{}, lines[start:i]...), stripped)
+		return strings.Join(block, "\n"), true
+	}
+
+	return "", false
+}
+
+// listItemRegex matches markdown list item lines
+var listItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+
+// isFenceLine returns true if the line opens or closes a fenced code block
+func isFenceLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
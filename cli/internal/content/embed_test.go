@@ -0,0 +1,111 @@
+package content
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractEmbeds(t *testing.T) {
+	got := ExtractEmbeds("![[Note]] ![[photo.png]] ![[ notes/other#Heading ]] ![[Note.md#^block|alt]] ![[doc.pdf]]")
+	want := []Embed{
+		{Target: "Note", Raw: "![[Note]]"},
+		{Target: "notes/other", Fragment: "Heading", Raw: "![[ notes/other#Heading ]]"},
+		{Target: "Note.md", Fragment: "^block", Raw: "![[Note.md#^block|alt]]"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractEmbeds() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestExtractSection(t *testing.T) {
+	content := "# Title\n\nIntro\n\n## Soil\n\nLoam.\n\n### Clay\n\nHeavy.\n\n```\n## Not a heading\n```\n\n## Water\n\nRain."
+	tests := []struct {
+		heading string
+		want    string
+		ok      bool
+	}{
+		{"Soil", "## Soil\n\nLoam.\n\n### Clay\n\nHeavy.\n\n```\n## Not a heading\n```\n", true},
+		{"clay", "### Clay\n\nHeavy.\n\n```\n## Not a heading\n```\n", true},
+		{"Water", "## Water\n\nRain.", true},
+		{"Not a heading", "", false},
+		{"Missing", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := extractSection(content, tt.heading)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("extractSection(%q) = %q, %v, want %q, %v", tt.heading, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExtractBlock(t *testing.T) {
+	content := "First line\nsecond line ^para\n\n- one\n- two ^item\n\nA quote\n\n^above\n\n```\ncode ^fenced\n```"
+	tests := []struct {
+		id   string
+		want string
+		ok   bool
+	}{
+		{"para", "First line\nsecond line", true},
+		{"item", "- two", true},
+		{"above", "A quote", true},
+		{"fenced", "", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := extractBlock(content, tt.id)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("extractBlock(%q) = %q, %v, want %q, %v", tt.id, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRenderEmbeds(t *testing.T) {
+	pages := renderNotes(t, map[string]string{
+		"garden.md": "---\ntitle: Garden & Co\n---\nIntro.\n\n## Soil\n\nLoam. ^loam\n",
+		"loop.md":   "---\ntitle: Loop\n---\n![[loop]]\n",
+		"embeds.md": "---\ntitle: Embeds\n---\n![[garden]]\n\n![[garden#Soil]]\n\n![[garden#^loam]]\n\n" +
+			"![[garden#Nowhere]]\n\n![[missing <script>]]\n",
+	}, RenderOptions{Wikilinks: true, BasePath: "/site"})
+	embeds := pages["embeds"]
+
+	for _, want := range []string{
+		"<div class=\"lp-embed\" data-embed-src=\"/site/garden/\">\n<div class=\"lp-embed-title\"><a class=\"lp-wikilink\" href=\"/site/garden/\">Garden &amp; Co</a></div>\n<div class=\"lp-embed-content\">\n<p>Intro.</p>",
+		`<a class="lp-wikilink" href="/site/garden/#soil">Garden &amp; Co</a>`,
+		`<a class="lp-wikilink" href="/site/garden/#^loam">Garden &amp; Co</a>`,
+		"<div class=\"lp-embed-content\">\n<p>Loam.</p>",
+		`<span class="lp-broken-link">[[garden#Nowhere]]</span>`,
+		`<span class="lp-broken-link">[[missing &lt;script&gt;]]</span>`,
+	} {
+		if !strings.Contains(embeds.HTMLContent, want) {
+			t.Errorf("embeds.md doesn't contain %s\n%s", want, embeds.HTMLContent)
+		}
+	}
+	if strings.Contains(embeds.HTMLContent, "<script>") {
+		t.Errorf("embeds.md contains an unescaped embed target\n%s", embeds.HTMLContent)
+	}
+
+	wantWarnings := []string{"missing heading: ![[garden#Nowhere]]", "broken embed: ![[missing <script>]]"}
+	if !reflect.DeepEqual(embeds.Warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", embeds.Warnings, wantWarnings)
+	}
+
+	loop := pages["loop"]
+	if !strings.Contains(loop.HTMLContent, `<div class="lp-embed lp-embed-error">Embed cycle: Loop</div>`) {
+		t.Errorf("loop.md doesn't report the embed cycle\n%s", loop.HTMLContent)
+	}
+	if len(loop.Warnings) != 1 || !strings.HasPrefix(loop.Warnings[0], "embed cycle: ![[loop]]") {
+		t.Errorf("loop.md warnings = %q, want an embed cycle", loop.Warnings)
+	}
+}
+
+func TestRenderEmbedDepth(t *testing.T) {
+	notes := map[string]string{}
+	for i := 0; i <= MaxEmbedDepth+1; i++ {
+		notes[string(rune('a'+i))+".md"] = "---\ntitle: Note " + string(rune('a'+i)) + "\n---\n![[" + string(rune('a'+i+1)) + "]]\n"
+	}
+	pages := renderNotes(t, notes, RenderOptions{Wikilinks: true})
+	if got := pages["a"].HTMLContent; !strings.Contains(got, `<div class="lp-embed lp-embed-error">Embed depth exceeded: `) {
+		t.Errorf("a.md doesn't report the embed depth\n%s", got)
+	}
+}
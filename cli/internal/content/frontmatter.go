@@ -1,27 +1,37 @@
 package content
 
 import (
-	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
-// Frontmatter represents the YAML frontmatter of a page
+// Frontmatter represents the frontmatter of a page
 type Frontmatter struct {
 	Title       string   `yaml:"title"`
 	Description string   `yaml:"description"` // SEO meta description
 	Date        string   `yaml:"date"`
 	Tags        []string `yaml:"tags"`
 	Draft       bool     `yaml:"draft"`
+	Publish     *bool    `yaml:"publish"` // Publish in optIn mode (nil = only inside a publish folder)
 	Growth      string   `yaml:"growth"`
 	Sort        string   `yaml:"sort"`     // For _index.md files
 	TOC         *bool    `yaml:"toc"`      // Override site-wide TOC setting (nil = use site default)
 	ShowList    *bool    `yaml:"showList"` // Show page list on section index (nil = true)
 	Image       string   `yaml:"image"`    // OG image override for this page
 
+	// URL overrides
+	Slug      string `yaml:"slug"`      // Replaces the file name in the URL
+	Permalink string `yaml:"permalink"` // Replaces the whole URL path (e.g., /about/)
+
 	// Obsidian-compatible date aliases
 	Created   string `yaml:"created"`   // Alias for date (creation date)
 	CreatedAt string `yaml:"createdAt"` // Alias for date (creation date)
@@ -31,62 +41,362 @@ type Frontmatter struct {
 
 	// Reading time override
 	ReadingTime *int `yaml:"readingTime"` // Manual override for reading time in minutes
+
+	// Alternative names and old URLs
+	Aliases      StringList `yaml:"aliases"`       // Obsidian aliases (resolve [[Alias]] and get redirect pages)
+	RedirectFrom StringList `yaml:"redirect_from"` // Old slugs that should redirect to this page
+
+	// Every field as written, including custom ones like status or rating
+	Params map[string]any `yaml:"-"`
+}
+
+// builtinParams are the frontmatter fields leafpress interprets itself
+var builtinParams = map[string]bool{
+	"title": true, "description": true, "date": true, "tags": true, "draft": true, "publish": true,
+	"growth": true, "sort": true, "toc": true, "showList": true, "image": true, "slug": true, "permalink": true,
+	"created": true, "createdAt": true, "modified": true, "updated": true, "updatedAt": true,
+	"readingTime": true, "aliases": true, "redirect_from": true,
+}
+
+// StringList is a YAML field that accepts either a single string or a list of strings
+type StringList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler for StringList
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if value.Value != "" {
+			*l = StringList{value.Value}
+		}
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := value.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	}
+	return fmt.Errorf("line %d: expected a string or list of strings", value.Line)
 }
 
-// ParseFrontmatter extracts frontmatter and content from markdown
+// FrontmatterError is an invalid or unclosed frontmatter block
+type FrontmatterError struct {
+	Path string // Source file, set by the scanner
+	Line int    // Line within the frontmatter (0 if unknown)
+	Msg  string
+}
+
+func (e *FrontmatterError) Error() string {
+	location := "frontmatter"
+	if e.Line > 0 {
+		location = fmt.Sprintf("frontmatter line %d", e.Line)
+	}
+	if e.Path != "" {
+		location = e.Path + ": " + location
+	}
+	return location + ": " + e.Msg
+}
+
+var (
+	// yamlErrorLineRegex matches the line number in yaml.v3 error messages
+	yamlErrorLineRegex = regexp.MustCompile(`line (\d+): (.*)`)
+	// jsonCloseLineRegex matches a line holding only }, followed by a newline
+	jsonCloseLineRegex = regexp.MustCompile(`(?m)^[ \t]*}[ \t]*\n`)
+)
+
+// ParseFrontmatter extracts frontmatter and content from markdown. Supports
+// YAML (---), TOML (+++) and JSON ({...}) frontmatter, with or without a
+// byte order mark and Windows line endings.
 func ParseFrontmatter(content string) (*Frontmatter, string, error) {
-	scanner := bufio.NewScanner(strings.NewReader(content))
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
 
-	// Check for frontmatter delimiter
-	if !scanner.Scan() {
-		return &Frontmatter{}, content, nil
+	if isJSONFrontmatter(content) {
+		return parseJSONFrontmatter(content)
 	}
 
-	firstLine := scanner.Text()
-	if firstLine != "---" {
+	firstLine, rest, _ := strings.Cut(content, "\n")
+	delimiter := strings.TrimRight(firstLine, " \t")
+	if delimiter != "---" && delimiter != "+++" {
 		// No frontmatter
 		return &Frontmatter{}, content, nil
 	}
 
-	// Read frontmatter lines
-	var fmLines []string
-	foundEnd := false
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "---" {
-			foundEnd = true
+	// Find the closing delimiter
+	lines := strings.Split(rest, "\n")
+	end := -1
+	for i, line := range lines {
+		if strings.TrimRight(line, " \t") == delimiter {
+			end = i
 			break
 		}
-		fmLines = append(fmLines, line)
 	}
+	if end < 0 {
+		return nil, "", &FrontmatterError{Msg: "unclosed frontmatter: missing closing " + delimiter}
+	}
+
+	block := strings.Join(lines[:end], "\n")
+	// Trim leading newlines from body
+	body := strings.TrimLeft(strings.Join(lines[end+1:], "\n"), "\n")
+
+	var fm *Frontmatter
+	var err error
+	if delimiter == "+++" {
+		fm, err = decodeTOMLFrontmatter(block)
+	} else {
+		fm, err = decodeYAMLFrontmatter(block)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := validateFrontmatter(fm, block); err != nil {
+		return nil, "", err
+	}
+	return fm, body, nil
+}
+
+// isJSONFrontmatter reports whether content starts with a JSON object whose
+// closing brace could be on a line of its own, followed by a newline
+func isJSONFrontmatter(content string) bool {
+	if !strings.HasPrefix(content, "{") {
+		return false
+	}
+	rest := strings.TrimLeft(content[1:], " \t\n")
+	if !strings.HasPrefix(rest, `"`) && !strings.HasPrefix(rest, "}") {
+		return false
+	}
+	return jsonCloseLineRegex.MatchString(content)
+}
+
+// endsOnOwnLine reports whether the JSON object that makes up the start of
+// content[:end] ends with } on its own line, followed by a newline
+func endsOnOwnLine(content string, end int) bool {
+	lineStart := strings.LastIndexByte(content[:end], '\n') + 1
+	if lineStart == 0 || strings.TrimSpace(content[lineStart:end]) != "}" {
+		return false
+	}
+	rest := content[end:]
+	i := strings.IndexByte(rest, '\n')
+	return i >= 0 && strings.TrimSpace(rest[:i]) == ""
+}
+
+// parseJSONFrontmatter parses frontmatter written as a JSON object, followed by the body
+func parseJSONFrontmatter(content string) (*Frontmatter, string, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(err, &syntaxErr):
+			line := strings.Count(content[:syntaxErr.Offset], "\n") + 1
+			return nil, "", &FrontmatterError{Line: line, Msg: "invalid JSON: " + syntaxErr.Error()}
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return nil, "", &FrontmatterError{Msg: "unclosed frontmatter: missing closing }"}
+		}
+		return nil, "", &FrontmatterError{Msg: "invalid JSON: " + err.Error()}
+	}
+
+	end := int(decoder.InputOffset())
+	if !endsOnOwnLine(content, end) {
+		// Just a body that starts with an object, like {"a": 1} in text
+		return &Frontmatter{}, content, nil
+	}
+	block := content[:end]
+	// The body starts on the line after the closing brace
+	body := content[len(block):]
+	if i := strings.IndexByte(body, '\n'); i >= 0 && strings.TrimSpace(body[:i]) == "" {
+		body = body[i+1:]
+	}
+	body = strings.TrimLeft(body, "\n")
+
+	// JSON is valid YAML, so it decodes the same way and keeps line numbers
+	fm, err := decodeYAMLFrontmatter(block)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := validateFrontmatter(fm, block); err != nil {
+		return nil, "", err
+	}
+	return fm, body, nil
+}
+
+// decodeYAMLFrontmatter decodes a YAML frontmatter block
+func decodeYAMLFrontmatter(block string) (*Frontmatter, error) {
+	fm := &Frontmatter{}
+	if err := yaml.Unmarshal([]byte(block), fm); err != nil {
+		return nil, yamlFrontmatterError(err)
+	}
+	var params map[string]any
+	if err := yaml.Unmarshal([]byte(block), &params); err == nil {
+		fm.Params = normalizeParams(params)
+	}
+	return fm, nil
+}
 
-	if !foundEnd {
-		return nil, "", fmt.Errorf("unclosed frontmatter: missing closing ---")
+// decodeTOMLFrontmatter decodes a TOML frontmatter block. The fields are
+// re-encoded as YAML so they decode exactly like YAML frontmatter.
+func decodeTOMLFrontmatter(block string) (*Frontmatter, error) {
+	params := make(map[string]any)
+	if _, err := toml.Decode(block, &params); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &FrontmatterError{Line: parseErr.Position.Line, Msg: parseErr.Message}
+		}
+		return nil, &FrontmatterError{Msg: err.Error()}
 	}
+	params = normalizeParams(params)
 
-	// Parse YAML
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return nil, &FrontmatterError{Msg: err.Error()}
+	}
 	fm := &Frontmatter{}
-	fmContent := strings.Join(fmLines, "\n")
-	if err := yaml.Unmarshal([]byte(fmContent), fm); err != nil {
-		return nil, "", fmt.Errorf("invalid frontmatter YAML: %w", err)
+	if err := yaml.Unmarshal(data, fm); err != nil {
+		// Lines in the re-encoded YAML don't match the TOML source
+		fmErr := yamlFrontmatterError(err)
+		fmErr.Line = 0
+		return nil, fmErr
 	}
+	fm.Params = params
+	return fm, nil
+}
 
+// yamlFrontmatterError converts a yaml.v3 error into a FrontmatterError with its line
+func yamlFrontmatterError(err error) *FrontmatterError {
+	msg := err.Error()
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	if match := yamlErrorLineRegex.FindStringSubmatch(msg); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return &FrontmatterError{Line: line, Msg: match[2]}
+	}
+	return &FrontmatterError{Msg: strings.TrimPrefix(msg, "yaml: ")}
+}
+
+// validateFrontmatter checks field values that decode fine but aren't allowed
+func validateFrontmatter(fm *Frontmatter, block string) error {
 	// Validate growth value
 	if fm.Growth != "" && fm.Growth != "seedling" && fm.Growth != "budding" && fm.Growth != "evergreen" {
-		return nil, "", fmt.Errorf("invalid growth value: %s (must be seedling, budding, or evergreen)", fm.Growth)
+		return &FrontmatterError{
+			Line: fieldLine(block, "growth"),
+			Msg:  fmt.Sprintf("invalid growth value: %s (must be seedling, budding, or evergreen)", fm.Growth),
+		}
 	}
 
-	// Collect remaining content
-	var bodyLines []string
-	for scanner.Scan() {
-		bodyLines = append(bodyLines, scanner.Text())
+	// Validate URL overrides
+	fm.Slug = strings.TrimSpace(fm.Slug)
+	if strings.Contains(fm.Slug, "/") || fm.Slug == "." || fm.Slug == ".." {
+		return &FrontmatterError{
+			Line: fieldLine(block, "slug"),
+			Msg:  fmt.Sprintf("invalid slug: %s (must be a single URL segment, use permalink for full paths)", fm.Slug),
+		}
 	}
+	fm.Permalink = strings.TrimSpace(fm.Permalink)
+	if strings.Trim(fm.Permalink, "/") != "" && !validURLPath(fm.Permalink) {
+		return &FrontmatterError{
+			Line: fieldLine(block, "permalink"),
+			Msg:  fmt.Sprintf("invalid permalink: %s", fm.Permalink),
+		}
+	}
+	for _, from := range fm.RedirectFrom {
+		if !validURLPath(strings.TrimSuffix(strings.TrimSpace(from), ".md")) {
+			return &FrontmatterError{
+				Line: fieldLine(block, "redirect_from"),
+				Msg:  fmt.Sprintf("invalid redirect_from entry: %q", from),
+			}
+		}
+	}
+	return nil
+}
 
-	body := strings.Join(bodyLines, "\n")
-	// Trim leading newlines from body
-	body = strings.TrimLeft(body, "\n")
+// validURLPath reports whether a site-relative URL path is non-empty and has
+// no empty, "." or ".." segments, so it can't escape the output directory
+func validURLPath(p string) bool {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return false
+	}
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
 
-	return fm, body, nil
+// FrontmatterFieldLine returns the line (1-based) of a top-level frontmatter
+// field in a markdown file, or 0 if it isn't there
+func FrontmatterFieldLine(content, field string) int {
+	content = strings.ReplaceAll(strings.TrimPrefix(content, "\ufeff"), "\r\n", "\n")
+	_, body, err := ParseFrontmatter(content)
+	if err != nil || !strings.HasSuffix(content, body) {
+		return 0
+	}
+	return fieldLine(content[:len(content)-len(body)], field)
+}
+
+// fieldLine returns the line of a top-level field in a frontmatter block (0 if not found)
+func fieldLine(block, field string) int {
+	for i, line := range strings.Split(block, "\n") {
+		line = strings.TrimLeft(line, " \t{,")
+		line = strings.TrimPrefix(strings.TrimPrefix(line, `"`), "'")
+		if strings.HasPrefix(line, field) {
+			rest := strings.TrimLeft(line[len(field):], `"' `+"\t")
+			if strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "=") {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// normalizeParams converts nested YAML maps with non-string keys to
+// map[string]any, so params can always be encoded as JSON
+func normalizeParams(params map[string]any) map[string]any {
+	for key, value := range params {
+		params[key] = normalizeParam(value)
+	}
+	return params
+}
+
+func normalizeParam(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return normalizeParams(v)
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeParam(item)
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeParam(item)
+		}
+		return v
+	case []map[string]any:
+		// TOML [[arrays]] of tables
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = normalizeParams(item)
+		}
+		return items
+	case time.Time:
+		// TOML dates and times are kept as written, like YAML ones
+		switch v.Location().String() {
+		case "date-local":
+			return v.Format("2006-01-02")
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05.999999999")
+		case "time-local":
+			return v.Format("15:04:05.999999999")
+		}
+		return v.Format(time.RFC3339Nano)
+	}
+	return value
 }
 
 // ParseDate parses the date string from frontmatter
@@ -99,6 +409,7 @@ func ParseDate(dateStr string) (time.Time, error) {
 		"2006-01-02",
 		"2006-01-02T15:04:05Z",
 		"2006-01-02T15:04:05-07:00",
+		"2006-01-02T15:04:05", // TOML local date-times
 		"2006-01-02 15:04:05",
 		"January 2, 2006",
 		"Jan 2, 2006",
@@ -0,0 +1,123 @@
+package content
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFrontmatterTOML(t *testing.T) {
+	content := `+++
+title = "Garden"
+tags = ["plants", "outdoors"]
+draft = true
+toc = false
+readingTime = 7
+aliases = "Yard"
+date = 2024-05-01
+modified = 2024-06-02T10:30:00
+updated = 2024-06-03T10:30:00+02:00
+status = "growing"
+reviewed = 09:15:00
+
+[[sources]]
+name = "Book"
++++
+
+Body text.
+`
+	fm, body, err := ParseFrontmatter(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "Body text.\n" {
+		t.Errorf("body = %q", body)
+	}
+	if fm.Title != "Garden" || !fm.Draft || fm.TOC == nil || *fm.TOC || fm.ReadingTime == nil || *fm.ReadingTime != 7 {
+		t.Errorf("fields = %+v", fm)
+	}
+	if !reflect.DeepEqual(fm.Tags, []string{"plants", "outdoors"}) || !reflect.DeepEqual([]string(fm.Aliases), []string{"Yard"}) {
+		t.Errorf("tags = %q, aliases = %q", fm.Tags, fm.Aliases)
+	}
+
+	// Dates are kept as written and parse like YAML ones
+	dates := []struct {
+		field string
+		got   string
+		want  string
+	}{
+		{"date", fm.Date, "2024-05-01"},
+		{"modified", fm.Modified, "2024-06-02T10:30:00"},
+		{"updated", fm.Updated, "2024-06-03T10:30:00+02:00"},
+	}
+	for _, d := range dates {
+		if d.got != d.want {
+			t.Errorf("%s = %q, want %q", d.field, d.got, d.want)
+		}
+		if _, err := ParseDate(d.got); err != nil {
+			t.Errorf("ParseDate(%s): %v", d.field, err)
+		}
+	}
+
+	if fm.Params["status"] != "growing" || fm.Params["reviewed"] != "09:15:00" {
+		t.Errorf("params = %v", fm.Params)
+	}
+	sources, ok := fm.Params["sources"].([]any)
+	if !ok || len(sources) != 1 || !reflect.DeepEqual(sources[0], map[string]any{"name": "Book"}) {
+		t.Errorf("sources = %#v", fm.Params["sources"])
+	}
+}
+
+func TestParseFrontmatterTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+	}{
+		{"syntax error", "+++\ntitle = \"Garden\"\ntags = [\n+++\n", 2},
+		{"wrong type", "+++\ndraft = \"maybe\"\n+++\n", 0},
+		{"unclosed", "+++\ntitle = \"Garden\"\n", 0},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseFrontmatter(tt.content)
+		var fmErr *FrontmatterError
+		if !errors.As(err, &fmErr) {
+			t.Errorf("%s: error = %v, want a FrontmatterError", tt.name, err)
+			continue
+		}
+		if fmErr.Line != tt.line {
+			t.Errorf("%s: line = %d, want %d (%v)", tt.name, fmErr.Line, tt.line, err)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"", time.Time{}},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-05-01T10:30:00Z", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{"2024-05-01T10:30:00", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{"2024-05-01T10:30:00.25", time.Date(2024, 5, 1, 10, 30, 0, 250000000, time.UTC)},
+		{"2024-05-01 10:30:00", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{"May 1, 2024", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"January 1, 2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.input)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	offset, err := ParseDate("2024-05-01T10:30:00+02:00")
+	if err != nil || !offset.Equal(time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("ParseDate with an offset = %v, %v", offset, err)
+	}
+	if _, err := ParseDate("01/05/2024"); err == nil {
+		t.Error("ParseDate(01/05/2024) didn't fail")
+	}
+}
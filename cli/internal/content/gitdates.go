@@ -0,0 +1,87 @@
+package content
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileDates are the first and last commit times of a file
+type FileDates struct {
+	Created  time.Time
+	Modified time.Time
+}
+
+// GitDates reads commit times for every markdown file under rootDir from git
+// history, in a single git log pass. Paths are relative to rootDir with
+// forward slashes. shallow is true for shallow clones, where the oldest
+// fetched commit stands in for the real creation date.
+func GitDates(rootDir string) (dates map[string]FileDates, shallow bool, err error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, false, fmt.Errorf("git not found")
+	}
+
+	out, err := gitOutput(rootDir, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return nil, false, fmt.Errorf("not a git repository")
+	}
+	shallow = strings.TrimSpace(string(out)) == "true"
+
+	// Newest commits first: each commit is a \x00-prefixed timestamp line
+	// followed by the files it touched
+	out, err = gitOutput(rootDir, "-c", "core.quotePath=false", "log",
+		"--format=%x00%ct", "--name-only", "--no-renames", "--relative", "--", ".")
+	if err != nil {
+		return nil, shallow, err
+	}
+
+	dates = make(map[string]FileDates)
+	var commitTime time.Time
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if ts, ok := strings.CutPrefix(line, "\x00"); ok {
+			secs, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return nil, shallow, fmt.Errorf("unexpected git log output: %q", line)
+			}
+			commitTime = time.Unix(secs, 0)
+			continue
+		}
+		if !strings.HasSuffix(line, ".md") {
+			continue
+		}
+
+		d, seen := dates[line]
+		if !seen {
+			d.Modified = commitTime
+		}
+		d.Created = commitTime
+		dates[line] = d
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, shallow, err
+	}
+
+	return dates, shallow, nil
+}
+
+// gitOutput runs a git command in dir, returning its stdout
+func gitOutput(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git: %s", msg)
+		}
+		return nil, fmt.Errorf("git: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,197 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	nonASCIIRegex   = regexp.MustCompile(`[^\x00-\x7F]+`)
+	nonAlphaNumeric = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// HeadingID creates a URL-safe ID from heading text
+// This is the ID scheme used for heading anchors, the TOC and [[note#Heading]] links
+func HeadingID(text string) string {
+	// Remove emojis and other non-ASCII characters first
+	id := nonASCIIRegex.ReplaceAllString(text, "")
+
+	// Trim spaces that may be left after emoji removal
+	id = strings.TrimSpace(id)
+
+	// Convert to lowercase
+	id = strings.ToLower(id)
+
+	// Replace spaces and special characters with hyphens
+	id = nonAlphaNumeric.ReplaceAllString(id, "-")
+
+	// Remove leading/trailing hyphens
+	id = strings.Trim(id, "-")
+
+	return id
+}
+
+// headingText returns heading markdown as it reads on the page, with
+// wiki-links replaced by their labels
+func headingText(markdown string) string {
+	return wikiLinkRegex.ReplaceAllStringFunc(markdown, func(match string) string {
+		submatches := wikiLinkRegex.FindStringSubmatch(match)
+		return newWikiLink(submatches[1], submatches[2], match).Label
+	})
+}
+
+// BlockAnchor returns the HTML id used for an Obsidian block ID (^block-id)
+func BlockAnchor(id string) string {
+	return "^" + strings.TrimPrefix(id, "^")
+}
+
+// FragmentAnchor converts a wiki-link fragment (Heading or ^block-id) to an HTML id
+func FragmentAnchor(fragment string) string {
+	if strings.HasPrefix(fragment, "^") {
+		return BlockAnchor(fragment)
+	}
+	return HeadingID(fragment)
+}
+
+// headingIDs implements goldmark's parser.IDs using HeadingID so rendered
+// heading anchors match the TOC and wiki-link fragments
+type headingIDs struct {
+	used map[string]int
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: make(map[string]int)}
+}
+
+// Generate returns a unique ID for a heading, suffixing duplicates with -1, -2, ...
+func (h *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	id := HeadingID(htmlTagRegex.ReplaceAllString(headingText(string(value)), ""))
+	if id == "" {
+		id = "heading"
+	}
+	return []byte(h.unique(id))
+}
+
+// Put marks an explicit ID as used
+func (h *headingIDs) Put(value []byte) {
+	h.used[string(value)]++
+}
+
+func (h *headingIDs) unique(id string) string {
+	count, exists := h.used[id]
+	h.used[id] = count + 1
+	if !exists {
+		return id
+	}
+	for i := count; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", id, i)
+		if _, taken := h.used[candidate]; !taken {
+			h.used[candidate] = 1
+			return candidate
+		}
+	}
+}
+
+// ExtractAnchors returns the heading and block anchors defined by markdown content
+func ExtractAnchors(content string) map[string]bool {
+	anchors := make(map[string]bool)
+	ids := newHeadingIDs()
+
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if matches := atxHeadingRegex.FindStringSubmatch(line); matches != nil {
+			anchors[string(ids.Generate([]byte(matches[2]), ast.KindHeading))] = true
+		}
+		if matches := blockIDRegex.FindStringSubmatch(line); matches != nil {
+			anchors[BlockAnchor(matches[1])] = true
+		}
+	}
+
+	return anchors
+}
+
+// anchorCache memoizes ExtractAnchors per page for link validation
+type anchorCache struct {
+	mu      sync.Mutex
+	anchors map[*Page]map[string]bool
+}
+
+func (c *anchorCache) get(page *Page) map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.anchors == nil {
+		c.anchors = make(map[*Page]map[string]bool)
+	}
+	if anchors, ok := c.anchors[page]; ok {
+		return anchors
+	}
+	anchors := ExtractAnchors(page.RawContent)
+	c.anchors[page] = anchors
+	return anchors
+}
+
+// blockAnchorRegex matches an Obsidian block ID marker running to the end of the line
+var blockAnchorRegex = regexp.MustCompile(`^\^([A-Za-z0-9-]+)[ \t]*\r?\n?$`)
+
+// KindBlockAnchor is the NodeKind for block IDs
+var KindBlockAnchor = ast.NewNodeKind("BlockAnchor")
+
+// BlockAnchorNode is an Obsidian ^block-id marker, rendered as a link target
+type BlockAnchorNode struct {
+	ast.BaseInline
+	ID string
+}
+
+// Kind implements ast.Node.Kind
+func (n *BlockAnchorNode) Kind() ast.NodeKind { return KindBlockAnchor }
+
+// Dump implements ast.Node.Dump
+func (n *BlockAnchorNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"ID": n.ID}, nil)
+}
+
+// blockAnchorParser turns ^block-id markers at the end of a line into anchor targets
+type blockAnchorParser struct{}
+
+func (p *blockAnchorParser) Trigger() []byte {
+	return []byte{'^'}
+}
+
+func (p *blockAnchorParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if prev := block.PrecendingCharacter(); prev != '\n' && prev != ' ' && prev != '\t' {
+		return nil
+	}
+	line, _ := block.PeekLine()
+	matches := blockAnchorRegex.FindSubmatchIndex(line)
+	if matches == nil {
+		return nil
+	}
+	// Leave the line ending for goldmark's line break handling
+	block.Advance(len(bytes.TrimRight(line, " \t\r\n")))
+	return &BlockAnchorNode{ID: string(line[matches[2]:matches[3]])}
+}
+
+// renderBlockAnchor renders a block ID as an empty span carrying the anchor
+func renderBlockAnchor(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString(`<span class="lp-block-anchor" id="`)
+		w.WriteString(BlockAnchor(node.(*BlockAnchorNode).ID))
+		w.WriteString(`"></span>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,70 @@
+package content
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindHighlight is the NodeKind for ==highlighted== text
+var KindHighlight = ast.NewNodeKind("Highlight")
+
+// Highlight is ==highlighted== text, rendered as <mark>
+type Highlight struct {
+	ast.BaseInline
+}
+
+// Kind implements ast.Node.Kind
+func (n *Highlight) Kind() ast.NodeKind { return KindHighlight }
+
+// Dump implements ast.Node.Dump
+func (n *Highlight) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// highlightDelimiterProcessor pairs == delimiters, like ~~ for strikethrough
+type highlightDelimiterProcessor struct{}
+
+func (p *highlightDelimiterProcessor) IsDelimiter(b byte) bool {
+	return b == '='
+}
+
+func (p *highlightDelimiterProcessor) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char
+}
+
+func (p *highlightDelimiterProcessor) OnMatch(consumes int) ast.Node {
+	return &Highlight{}
+}
+
+var defaultHighlightDelimiterProcessor = &highlightDelimiterProcessor{}
+
+// highlightParser parses ==text== delimiters. Single = signs (a = b) are left alone.
+type highlightParser struct{}
+
+func (p *highlightParser) Trigger() []byte {
+	return []byte{'='}
+}
+
+func (p *highlightParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 2, defaultHighlightDelimiterProcessor)
+	if node == nil || node.OriginalLength != 2 || before == '=' {
+		return nil
+	}
+	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
+	block.Advance(node.OriginalLength)
+	pc.PushDelimiter(node)
+	return node
+}
+
+func renderHighlight(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString("<mark>")
+	} else {
+		w.WriteString("</mark>")
+	}
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,336 @@
+package content
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// leafpressExtension adds leafpress's markdown syntax to goldmark:
+// wiki-links, note and image embeds, callouts, block IDs, inline tags,
+// ==highlights== and leafpress-query blocks, plus external link, lazy image
+// and blockquote citation decoration
+type leafpressExtension struct {
+	renderer *Renderer
+}
+
+func (e *leafpressExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&calloutParser{types: e.renderer.calloutTypes}, 799), // Before blockquotes
+		),
+		parser.WithInlineParsers(
+			util.Prioritized(&wikiLinkParser{renderer: e.renderer}, 199), // Before links
+			util.Prioritized(&blockAnchorParser{}, 600),
+			util.Prioritized(&tagParser{renderer: e.renderer}, 600),
+			util.Prioritized(&highlightParser{}, 500),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(&decorationTransformer{}, 100),
+			util.Prioritized(&queryTransformer{renderer: e.renderer}, 100),
+			util.Prioritized(&sourceMapTransformer{}, 50), // After the others rewrite links
+		),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(util.Prioritized(&leafpressHTMLRenderer{}, 100)),
+	)
+}
+
+// leafpressHTMLRenderer renders leafpress AST nodes
+type leafpressHTMLRenderer struct{}
+
+func (r *leafpressHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindWikiLink, renderWikiLink)
+	reg.Register(KindEmbed, renderEmbedNode)
+	reg.Register(KindCallout, renderCallout)
+	reg.Register(KindCalloutTitle, renderCalloutTitle)
+	reg.Register(KindBlockAnchor, renderBlockAnchor)
+	reg.Register(KindTag, renderTag)
+	reg.Register(KindQuery, renderQuery)
+	reg.Register(KindHighlight, renderHighlight)
+	reg.Register(KindCite, renderCite)
+	reg.Register(ast.KindLink, renderLink)
+	reg.Register(ast.KindImage, renderImage)
+	reg.Register(ast.KindRawHTML, renderRawHTML)
+	reg.Register(ast.KindHTMLBlock, renderHTMLBlock)
+}
+
+// renderRawHTML writes inline HTML as-is, adding lazy loading to images
+func renderRawHTML(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*ast.RawHTML)
+		var html bytes.Buffer
+		for i := 0; i < n.Segments.Len(); i++ {
+			segment := n.Segments.At(i)
+			html.Write(segment.Value(source))
+		}
+		w.WriteString(processLazyImages(html.String()))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// renderHTMLBlock writes HTML blocks as-is, adding lazy loading to images
+func renderHTMLBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.HTMLBlock)
+	if entering {
+		var html bytes.Buffer
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			html.Write(line.Value(source))
+		}
+		w.WriteString(processLazyImages(html.String()))
+	} else if n.HasClosure() {
+		w.Write(n.ClosureLine.Value(source))
+	}
+	return ast.WalkContinue, nil
+}
+
+// KindCite is the NodeKind for blockquote citations
+var KindCite = ast.NewNodeKind("Cite")
+
+// Cite is the attribution at the end of a blockquote
+type Cite struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind
+func (n *Cite) Kind() ast.NodeKind { return KindCite }
+
+// Dump implements ast.Node.Dump
+func (n *Cite) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+func renderCite(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString("<cite>")
+	} else {
+		w.WriteString("</cite>")
+	}
+	return ast.WalkContinue, nil
+}
+
+// renderLink writes a link with its class first and its title last, matching
+// the markup the renderer produced before it used AST extensions
+func renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		w.WriteString("</a>")
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Link)
+	w.WriteString("<a")
+	if class, ok := n.AttributeString("class"); ok {
+		w.WriteString(` class="`)
+		w.Write(util.EscapeHTML(class.([]byte)))
+		w.WriteByte('"')
+	}
+	w.WriteString(` href="`)
+	w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
+	w.WriteByte('"')
+	for _, attr := range n.Attributes() {
+		if string(attr.Name) == "class" || !html.LinkAttributeFilter.Contains(attr.Name) {
+			continue
+		}
+		w.WriteByte(' ')
+		w.Write(attr.Name)
+		w.WriteString(`="`)
+		w.Write(util.EscapeHTML(attr.Value.([]byte)))
+		w.WriteByte('"')
+	}
+	if n.Title != nil {
+		w.WriteString(` title="`)
+		html.DefaultWriter.Write(w, n.Title)
+		w.WriteByte('"')
+	}
+	w.WriteByte('>')
+	return ast.WalkContinue, nil
+}
+
+// renderImage writes an image as a plain <img ...> tag without the XHTML
+// slash, matching the markup the renderer produced before it used AST extensions
+func renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	w.WriteString(`<img src="`)
+	w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
+	w.WriteString(`" alt="`)
+	writeAltText(w, source, n)
+	w.WriteByte('"')
+	if n.Title != nil {
+		w.WriteString(` title="`)
+		html.DefaultWriter.Write(w, n.Title)
+		w.WriteByte('"')
+	}
+	html.RenderAttributes(w, n, html.ImageAttributeFilter)
+	w.WriteByte('>')
+	return ast.WalkSkipChildren, nil
+}
+
+// writeAltText writes the escaped text of an image's children
+func writeAltText(w util.BufWriter, source []byte, node ast.Node) {
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		switch n := c.(type) {
+		case *ast.Text:
+			html.DefaultWriter.RawWrite(w, n.Value(source))
+		case *ast.String:
+			html.DefaultWriter.RawWrite(w, n.Value)
+		default:
+			writeAltText(w, source, c)
+		}
+	}
+}
+
+// decorationTransformer adjusts the parsed document: external links open in a
+// new tab, images load lazily, blockquote attributions become <cite> and
+// note embeds are lifted out of otherwise empty paragraphs
+type decorationTransformer struct{}
+
+func (t *decorationTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	// Collect first; the tree can't be modified while walking it
+	var links, autoLinks, images []ast.Node
+	var blockquotes []*ast.Blockquote
+	var paragraphs []*ast.Paragraph
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Link:
+			links = append(links, n)
+		case *ast.AutoLink:
+			autoLinks = append(autoLinks, n)
+		case *ast.Image:
+			images = append(images, n)
+		case *ast.Blockquote:
+			blockquotes = append(blockquotes, n)
+		case *ast.Paragraph:
+			paragraphs = append(paragraphs, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, node := range autoLinks {
+		n := node.(*ast.AutoLink)
+		if n.AutoLinkType != ast.AutoLinkURL {
+			continue
+		}
+		// Convert to a regular link so it can be decorated like one
+		link := ast.NewLink()
+		link.Destination = n.URL(source)
+		link.AppendChild(link, ast.NewString(n.Label(source)))
+		n.Parent().ReplaceChild(n.Parent(), n, link)
+		links = append(links, link)
+	}
+
+	for _, node := range links {
+		decorateExternalLink(node.(*ast.Link))
+	}
+
+	for _, node := range images {
+		// Add lazy loading to images
+		node.SetAttributeString("loading", []byte("lazy"))
+		node.SetAttributeString("decoding", []byte("async"))
+	}
+
+	for _, blockquote := range blockquotes {
+		citeBlockquote(blockquote, source)
+	}
+
+	for _, paragraph := range paragraphs {
+		unwrapEmbeds(paragraph, source)
+	}
+}
+
+// decorateExternalLink adds target="_blank" and class to links to other sites
+func decorateExternalLink(link *ast.Link) {
+	dest := link.Destination
+	if !bytes.HasPrefix(dest, []byte("http://")) && !bytes.HasPrefix(dest, []byte("https://")) {
+		return
+	}
+	link.SetAttributeString("class", []byte("lp-external"))
+	link.SetAttributeString("target", []byte("_blank"))
+	link.SetAttributeString("rel", []byte("noopener"))
+	link.AppendChild(link, ast.NewString([]byte(" ↗")))
+}
+
+// citeBlockquote converts a closing "- Author" or "— Author" line of a blockquote to <cite>
+// Input:  > Quote text
+//
+//	>
+//	> — Author Name
+//
+// Output: <blockquote><p>Quote text</p><cite>Author Name</cite></blockquote>
+// Also handles "> - Author", which markdown parses as a single-item list
+func citeBlockquote(blockquote *ast.Blockquote, source []byte) {
+	last := blockquote.LastChild()
+	if last == nil {
+		return
+	}
+	for c := blockquote.FirstChild(); c != last; c = c.NextSibling() {
+		if _, ok := c.(*ast.Paragraph); !ok {
+			return
+		}
+	}
+
+	var content ast.Node
+	switch n := last.(type) {
+	case *ast.Paragraph:
+		if !trimLeadingDash(n, source) {
+			return
+		}
+		content = n
+	case *ast.List:
+		item := n.FirstChild()
+		if n.IsOrdered() || n.ChildCount() != 1 || item.ChildCount() != 1 {
+			return
+		}
+		switch item.FirstChild().(type) {
+		case *ast.TextBlock, *ast.Paragraph:
+			content = item.FirstChild()
+		default:
+			return
+		}
+	default:
+		return
+	}
+
+	cite := &Cite{}
+	for c := content.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		cite.AppendChild(cite, c)
+		c = next
+	}
+	blockquote.ReplaceChild(blockquote, last, cite)
+}
+
+// trimLeadingDash removes a leading -, – or — (and following spaces) from a
+// paragraph, returning false if it doesn't start with one
+func trimLeadingDash(paragraph *ast.Paragraph, source []byte) bool {
+	first, ok := paragraph.FirstChild().(*ast.Text)
+	if !ok {
+		return false
+	}
+	value := first.Segment.Value(source)
+	trimmed := bytes.TrimLeft(value, " \t")
+	dash, size := utf8.DecodeRune(trimmed)
+	if dash != '-' && dash != '–' && dash != '—' {
+		return false
+	}
+	rest := bytes.TrimLeft(trimmed[size:], " \t")
+	if len(rest) == 0 && first.NextSibling() == nil {
+		return false // Nothing to attribute
+	}
+	first.Segment = first.Segment.WithStart(first.Segment.Stop - len(rest))
+	return true
+}
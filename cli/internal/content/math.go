@@ -0,0 +1,260 @@
+package content
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// inlineMathEnd returns the index of the $ closing the inline math that opens
+// at s[0], or -1. The opening $ must be followed by a non-space and the closing
+// $ preceded by a non-space and not followed by a digit, so "$5 and $10" stays
+// text. Inline math can't span lines or contain backticks.
+func inlineMathEnd(s []byte) int {
+	if len(s) < 3 || s[1] == '$' || util.IsSpace(s[1]) {
+		return -1
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\n', '`':
+			return -1
+		case '$':
+			if i == 1 || util.IsSpace(s[i-1]) || (i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9') {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// addMathWarning records a TeX conversion error in the math at offset
+func addMathWarning(pc parser.Context, offset int, tex string, err error) {
+	getRenderState(pc).warnAt(offset, "math: "+err.Error()+" in $"+tex+"$")
+}
+
+// KindMathInline is the NodeKind for inline math
+var KindMathInline = ast.NewNodeKind("MathInline")
+
+// MathInline is an inline $...$ math span
+type MathInline struct {
+	ast.BaseInline
+	TeX     string
+	MathML  string
+	Display bool // $$...$$ written inline
+}
+
+// Kind implements ast.Node.Kind
+func (n *MathInline) Kind() ast.NodeKind { return KindMathInline }
+
+// Dump implements ast.Node.Dump
+func (n *MathInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": n.TeX}, nil)
+}
+
+// KindMathBlock is the NodeKind for display math blocks
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// MathBlock is a $$...$$ display math block
+type MathBlock struct {
+	ast.BaseBlock
+	TeX    string
+	MathML string
+	closed bool // Closing $$ seen
+	offset int  // Byte offset of the opening $$
+}
+
+// Kind implements ast.Node.Kind
+func (n *MathBlock) Kind() ast.NodeKind { return KindMathBlock }
+
+// IsRaw implements ast.Node.IsRaw (content lines are TeX, not markdown)
+func (n *MathBlock) IsRaw() bool { return true }
+
+// Dump implements ast.Node.Dump
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": n.TeX}, nil)
+}
+
+// mathInlineParser parses $inline$ and single-line $$display$$ math
+type mathInlineParser struct{}
+
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+
+	display := len(line) > 1 && line[1] == '$'
+	var tex string
+	var consumed int
+	if display {
+		end := bytes.Index(line[2:], []byte("$$"))
+		if end < 0 {
+			return nil
+		}
+		tex = string(line[2 : 2+end])
+		consumed = end + 4
+	} else {
+		end := inlineMathEnd(line)
+		if end < 0 {
+			return nil
+		}
+		tex = string(line[1:end])
+		consumed = end + 1
+	}
+
+	if strings.TrimSpace(tex) == "" {
+		return nil
+	}
+	block.Advance(consumed)
+
+	mathML, err := TeXToMathML(tex, display)
+	if err != nil {
+		addMathWarning(pc, segment.Start, tex, err)
+	}
+	return &MathInline{TeX: tex, MathML: mathML, Display: display}
+}
+
+// mathBlockParser parses $$ ... $$ blocks spanning one or more lines
+type mathBlockParser struct{}
+
+func (p *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+
+	node := &MathBlock{offset: segment.Start + pos}
+	rest := bytes.TrimRight(line[pos+2:], " \t\r\n")
+
+	// Single-line block: $$ x = 1 $$
+	if end := bytes.Index(rest, []byte("$$")); end >= 0 {
+		if len(bytes.TrimSpace(rest[end+2:])) > 0 {
+			return nil, parser.NoChildren // Text after closing $$ - let the inline parser handle it
+		}
+		start := segment.Start + pos + 2
+		node.Lines().Append(text.NewSegment(start, start+end))
+		node.closed = true
+		reader.AdvanceToEOL()
+		return node, parser.NoChildren
+	}
+
+	if len(bytes.TrimSpace(rest)) > 0 {
+		start := segment.Start + pos + 2
+		node.Lines().Append(text.NewSegment(start, segment.Stop))
+	}
+	reader.AdvanceToEOL()
+	return node, parser.NoChildren
+}
+
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if line == nil || node.(*MathBlock).closed {
+		return parser.Close
+	}
+
+	trimmed := bytes.TrimRight(line, " \t\r\n")
+	if idx := bytes.LastIndex(trimmed, []byte("$$")); idx >= 0 && idx == len(trimmed)-2 {
+		if idx > 0 {
+			node.Lines().Append(text.NewSegment(segment.Start, segment.Start+idx))
+		}
+		node.(*MathBlock).closed = true
+		reader.AdvanceToEOL()
+		return parser.Continue | parser.NoChildren
+	}
+
+	node.Lines().Append(segment)
+	reader.AdvanceToEOL()
+	return parser.Continue | parser.NoChildren
+}
+
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	p.finish(node.(*MathBlock), reader.Source(), pc)
+}
+
+// finish converts the collected TeX lines to MathML
+func (p *mathBlockParser) finish(node *MathBlock, source []byte, pc parser.Context) {
+	var sb strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		sb.Write(segment.Value(source))
+	}
+	node.TeX = strings.TrimSpace(sb.String())
+
+	mathML, err := TeXToMathML(node.TeX, true)
+	if err != nil {
+		addMathWarning(pc, node.offset, node.TeX, err)
+	}
+	node.MathML = mathML
+}
+
+func (p *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// mathHTMLRenderer renders math nodes as MathML
+type mathHTMLRenderer struct{}
+
+func (r *mathHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMathInline, r.renderInline)
+	reg.Register(KindMathBlock, r.renderBlock)
+}
+
+func (r *mathHTMLRenderer) renderInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*MathInline)
+		if n.Display {
+			w.WriteString(`<span class="lp-math lp-math-display">`)
+		} else {
+			w.WriteString(`<span class="lp-math">`)
+		}
+		w.WriteString(n.MathML)
+		w.WriteString(`</span>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathHTMLRenderer) renderBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*MathBlock)
+		w.WriteString(`<div class="lp-math lp-math-display">`)
+		w.WriteString(n.MathML)
+		w.WriteString("</div>\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// mathExtension adds $inline$ and $$display$$ TeX math rendered to MathML at build time
+type mathExtension struct{}
+
+// MathExtension is a goldmark extension for server-side math rendering
+var MathExtension goldmark.Extender = &mathExtension{}
+
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 150)),
+		parser.WithInlineParsers(util.Prioritized(&mathInlineParser{}, 150)),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(util.Prioritized(&mathHTMLRenderer{}, 150)),
+	)
+}
@@ -0,0 +1,775 @@
+package content
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TeXToMathML converts a TeX math expression to MathML markup.
+// It supports the commonly used subset of LaTeX math (fractions, roots,
+// scripts, Greek letters, operators, accents, fonts and matrix environments).
+// Unsupported commands are rendered as <merror> and reported in the error.
+func TeXToMathML(tex string, display bool) (string, error) {
+	p := &texParser{toks: tokenizeTeX(tex), display: display}
+	body := p.parseRow(nil)
+	if p.pos < len(p.toks) {
+		p.errorf("unexpected %q", p.toks[p.pos].text)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"`)
+	if display {
+		sb.WriteString(` display="block"`)
+	}
+	sb.WriteString(`><semantics>`)
+	sb.WriteString(wrapRow(body))
+	sb.WriteString(`<annotation encoding="application/x-tex">`)
+	sb.WriteString(escapeMathText(strings.TrimSpace(tex)))
+	sb.WriteString(`</annotation></semantics></math>`)
+
+	if len(p.errs) > 0 {
+		return sb.String(), fmt.Errorf("%s", strings.Join(p.errs, "; "))
+	}
+	return sb.String(), nil
+}
+
+type texTokenKind int
+
+const (
+	texChar    texTokenKind = iota // Single character (letter, digit, operator)
+	texCommand                     // \name or \<symbol>
+	texOpen                        // {
+	texClose                       // }
+	texSup                         // ^
+	texSub                         // _
+	texAmp                         // &
+	texSpace                       // Whitespace (only meaningful inside \text)
+)
+
+type texToken struct {
+	kind texTokenKind
+	text string
+}
+
+// tokenizeTeX splits a TeX expression into tokens
+func tokenizeTeX(tex string) []texToken {
+	var toks []texToken
+	runes := []rune(tex)
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(runes) {
+				toks = append(toks, texToken{texChar, "\\"})
+				continue
+			}
+			j := i + 1
+			if unicode.IsLetter(runes[j]) {
+				for j < len(runes) && unicode.IsLetter(runes[j]) {
+					j++
+				}
+				toks = append(toks, texToken{texCommand, string(runes[i+1 : j])})
+				i = j - 1
+			} else {
+				// Control symbol: \\, \{, \,, \; ...
+				toks = append(toks, texToken{texCommand, string(runes[j])})
+				i = j
+			}
+		case c == '{':
+			toks = append(toks, texToken{texOpen, "{"})
+		case c == '}':
+			toks = append(toks, texToken{texClose, "}"})
+		case c == '^':
+			toks = append(toks, texToken{texSup, "^"})
+		case c == '_':
+			toks = append(toks, texToken{texSub, "_"})
+		case c == '&':
+			toks = append(toks, texToken{texAmp, "&"})
+		case c == '%':
+			// Comment: skip to end of line
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case unicode.IsSpace(c):
+			if len(toks) == 0 || toks[len(toks)-1].kind != texSpace {
+				toks = append(toks, texToken{texSpace, " "})
+			}
+		default:
+			toks = append(toks, texToken{texChar, string(c)})
+		}
+	}
+
+	return toks
+}
+
+type texParser struct {
+	toks    []texToken
+	pos     int
+	display bool
+	errs    []string
+}
+
+func (p *texParser) errorf(format string, args ...interface{}) {
+	p.errs = append(p.errs, fmt.Sprintf(format, args...))
+}
+
+func (p *texParser) peek() *texToken {
+	if p.pos < len(p.toks) {
+		return &p.toks[p.pos]
+	}
+	return nil
+}
+
+func (p *texParser) skipSpace() {
+	for p.pos < len(p.toks) && p.toks[p.pos].kind == texSpace {
+		p.pos++
+	}
+}
+
+// isStop reports whether the current token ends a row
+func (p *texParser) isStop(stops map[string]bool) bool {
+	tok := p.peek()
+	if tok == nil {
+		return true
+	}
+	switch tok.kind {
+	case texClose:
+		return true
+	case texAmp:
+		return stops["&"]
+	case texCommand:
+		return stops[`\`+tok.text] || tok.text == "right" || tok.text == "end"
+	}
+	return false
+}
+
+// parseRow parses atoms until a closing brace, \right, \end or a stop token
+func (p *texParser) parseRow(stops map[string]bool) []string {
+	var atoms []string
+	for {
+		p.skipSpace()
+		if p.isStop(stops) {
+			return atoms
+		}
+		atom, limits := p.parseAtom(false)
+		if atom == "" {
+			continue
+		}
+		atoms = append(atoms, p.parseScripts(atom, limits))
+	}
+}
+
+// parseScripts attaches ^, _ and primes following a base atom
+func (p *texParser) parseScripts(base string, limits bool) string {
+	var sub, sup string
+	for {
+		p.skipSpace()
+		tok := p.peek()
+		if tok == nil {
+			break
+		}
+		if tok.kind == texSup {
+			p.pos++
+			sup = p.parseArg()
+		} else if tok.kind == texSub {
+			p.pos++
+			sub = p.parseArg()
+		} else if tok.kind == texChar && tok.text == "'" {
+			p.pos++
+			sup += "<mo>′</mo>"
+		} else if tok.kind == texCommand && (tok.text == "limits" || tok.text == "nolimits") {
+			p.pos++
+			limits = tok.text == "limits"
+		} else {
+			break
+		}
+	}
+
+	switch {
+	case sub != "" && sup != "" && limits:
+		return "<munderover>" + base + wrapRow([]string{sub}) + wrapRow([]string{sup}) + "</munderover>"
+	case sub != "" && sup != "":
+		return "<msubsup>" + base + wrapRow([]string{sub}) + wrapRow([]string{sup}) + "</msubsup>"
+	case sub != "" && limits:
+		return "<munder>" + base + wrapRow([]string{sub}) + "</munder>"
+	case sub != "":
+		return "<msub>" + base + wrapRow([]string{sub}) + "</msub>"
+	case sup != "" && limits:
+		return "<mover>" + base + wrapRow([]string{sup}) + "</mover>"
+	case sup != "":
+		return "<msup>" + base + wrapRow([]string{sup}) + "</msup>"
+	}
+	return base
+}
+
+// parseArg parses a single argument: a {group} or a single token
+func (p *texParser) parseArg() string {
+	p.skipSpace()
+	tok := p.peek()
+	if tok == nil {
+		p.errorf("missing argument")
+		return "<mrow></mrow>"
+	}
+	if tok.kind == texOpen {
+		return wrapRow(p.parseGroup())
+	}
+	atom, _ := p.parseAtom(true)
+	return atom
+}
+
+// parseGroup parses {...} and returns its atoms
+func (p *texParser) parseGroup() []string {
+	p.pos++ // {
+	atoms := p.parseRow(nil)
+	if tok := p.peek(); tok != nil && tok.kind == texClose {
+		p.pos++
+	} else {
+		p.errorf("missing closing brace")
+	}
+	return atoms
+}
+
+// rawGroup returns the literal text of a {group} (used by \text and font commands)
+func (p *texParser) rawGroup() string {
+	p.skipSpace()
+	tok := p.peek()
+	if tok == nil {
+		p.errorf("missing argument")
+		return ""
+	}
+	if tok.kind != texOpen {
+		p.pos++
+		return tok.text
+	}
+
+	p.pos++
+	var sb strings.Builder
+	depth := 1
+	for p.pos < len(p.toks) {
+		tok := p.toks[p.pos]
+		p.pos++
+		switch tok.kind {
+		case texOpen:
+			depth++
+		case texClose:
+			depth--
+			if depth == 0 {
+				return sb.String()
+			}
+		case texCommand:
+			if sym, ok := texSymbols[tok.text]; ok {
+				sb.WriteString(sym.text)
+				continue
+			}
+			if len(tok.text) == 1 {
+				sb.WriteString(tok.text)
+				continue
+			}
+		}
+		if tok.kind != texOpen && tok.kind != texClose {
+			sb.WriteString(tok.text)
+		}
+	}
+	p.errorf("missing closing brace")
+	return sb.String()
+}
+
+// optionalArg parses [..] if present
+func (p *texParser) optionalArg() (string, bool) {
+	p.skipSpace()
+	tok := p.peek()
+	if tok == nil || tok.kind != texChar || tok.text != "[" {
+		return "", false
+	}
+	p.pos++
+	var atoms []string
+	for {
+		p.skipSpace()
+		tok := p.peek()
+		if tok == nil {
+			p.errorf("missing ]")
+			break
+		}
+		if tok.kind == texChar && tok.text == "]" {
+			p.pos++
+			break
+		}
+		atom, _ := p.parseAtom(false)
+		if atom != "" {
+			atoms = append(atoms, p.parseScripts(atom, false))
+		}
+	}
+	return wrapRow(atoms), true
+}
+
+// parseAtom parses a single atom. single limits numbers to one digit (for x^23).
+// The second return value reports whether scripts should be placed as limits.
+func (p *texParser) parseAtom(single bool) (string, bool) {
+	tok := p.peek()
+	if tok == nil {
+		return "", false
+	}
+
+	switch tok.kind {
+	case texOpen:
+		return wrapRow(p.parseGroup()), false
+	case texClose:
+		p.pos++
+		p.errorf("unexpected }")
+		return "", false
+	case texSup, texSub:
+		// Script without a base: attach to an empty row
+		return "<mrow></mrow>", false
+	case texAmp:
+		p.pos++
+		return "", false
+	case texSpace:
+		p.pos++
+		return "", false
+	case texCommand:
+		p.pos++
+		return p.parseCommand(tok.text)
+	}
+
+	p.pos++
+	c := []rune(tok.text)[0]
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		num := tok.text
+		if !single {
+			for p.pos < len(p.toks) && p.toks[p.pos].kind == texChar && isNumberChar(p.toks[p.pos].text) {
+				num += p.toks[p.pos].text
+				p.pos++
+			}
+		}
+		if num == "." {
+			return "<mo>.</mo>", false
+		}
+		return "<mn>" + num + "</mn>", false
+	case unicode.IsLetter(c):
+		return "<mi>" + escapeMathText(tok.text) + "</mi>", false
+	case c == '-':
+		return "<mo>−</mo>", false
+	case c == '*':
+		return "<mo>∗</mo>", false
+	case c == '~':
+		return `<mspace width="0.333em"></mspace>`, false
+	case c == '\'':
+		return "<mo>′</mo>", false
+	case c == '\\':
+		// Only a trailing backslash is left as a character
+		p.errorf("trailing \\")
+		return "<merror><mtext>\\</mtext></merror>", false
+	}
+	return "<mo>" + escapeMathText(tok.text) + "</mo>", false
+}
+
+func isNumberChar(s string) bool {
+	return len(s) == 1 && (s[0] >= '0' && s[0] <= '9' || s[0] == '.')
+}
+
+// parseCommand handles a \command whose name has already been consumed
+func (p *texParser) parseCommand(name string) (string, bool) {
+	if sym, ok := texSymbols[name]; ok {
+		return sym.render(), sym.limits && p.display
+	}
+	if fn, ok := texFunctions[name]; ok {
+		return "<mi>" + fn + "</mi>", texLimitFunctions[name] && p.display
+	}
+	if width, ok := texSpaces[name]; ok {
+		return `<mspace width="` + width + `"></mspace>`, false
+	}
+	if accent, ok := texAccents[name]; ok {
+		arg := p.parseArg()
+		if accent.under {
+			return `<munder accentunder="true">` + arg + `<mo stretchy="true">` + accent.mark + `</mo></munder>`, false
+		}
+		stretchy := "false"
+		if accent.stretchy {
+			stretchy = "true"
+		}
+		return `<mover accent="true">` + arg + `<mo stretchy="` + stretchy + `">` + accent.mark + `</mo></mover>`, false
+	}
+	if variant, ok := texFonts[name]; ok {
+		return mathAlphanumeric(p.rawGroup(), variant), false
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num := p.parseArg()
+		den := p.parseArg()
+		return "<mfrac>" + num + den + "</mfrac>", false
+	case "binom", "dbinom", "tbinom":
+		top := p.parseArg()
+		bottom := p.parseArg()
+		return `<mrow><mo>(</mo><mfrac linethickness="0">` + top + bottom + `</mfrac><mo>)</mo></mrow>`, false
+	case "sqrt":
+		if index, ok := p.optionalArg(); ok {
+			return "<mroot>" + p.parseArg() + index + "</mroot>", false
+		}
+		return "<msqrt>" + p.parseArg() + "</msqrt>", false
+	case "text", "textrm", "textit", "textbf", "mbox", "textnormal":
+		return "<mtext>" + escapeMathText(p.rawGroup()) + "</mtext>", false
+	case "left", "right", "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr", "biggl", "biggr", "middle":
+		if name == "left" {
+			return p.parseLeftRight(), false
+		}
+		if name == "right" {
+			p.errorf(`unmatched \right`)
+		}
+		return p.parseDelimiter(), false
+	case "begin":
+		return p.parseEnvironment(), false
+	case "end":
+		p.errorf(`unmatched \end`)
+		p.rawGroup()
+		return "", false
+	case `\`:
+		return `<mspace linebreak="newline"></mspace>`, false
+	case "displaystyle", "textstyle", "scriptstyle", "limits", "nolimits":
+		return "", false
+	case "not":
+		next, _ := p.parseAtom(true)
+		return "<mrow>" + next + "<mo>&#x338;</mo></mrow>", false
+	}
+
+	p.errorf(`unsupported command \%s`, name)
+	return "<merror><mtext>\\" + escapeMathText(name) + "</mtext></merror>", false
+}
+
+// parseDelimiter reads a delimiter after \left, \right or \big
+func (p *texParser) parseDelimiter() string {
+	p.skipSpace()
+	tok := p.peek()
+	if tok == nil {
+		p.errorf("missing delimiter")
+		return ""
+	}
+	p.pos++
+	text := tok.text
+	if tok.kind == texCommand {
+		if sym, ok := texSymbols[tok.text]; ok {
+			text = sym.text
+		} else {
+			p.errorf(`unsupported delimiter \%s`, tok.text)
+		}
+	}
+	if text == "." {
+		return ""
+	}
+	return `<mo fence="true" stretchy="true">` + escapeMathText(text) + `</mo>`
+}
+
+// parseLeftRight parses \left( ... \right) into a fenced row
+func (p *texParser) parseLeftRight() string {
+	open := p.parseDelimiter()
+	atoms := p.parseRow(nil)
+	close := ""
+	if tok := p.peek(); tok != nil && tok.kind == texCommand && tok.text == "right" {
+		p.pos++
+		close = p.parseDelimiter()
+	} else {
+		p.errorf(`\left without \right`)
+	}
+	return "<mrow>" + open + strings.Join(atoms, "") + close + "</mrow>"
+}
+
+// matrixFences maps matrix environments to their surrounding delimiters
+var matrixFences = map[string][2]string{
+	"matrix":      {"", ""},
+	"smallmatrix": {"", ""},
+	"pmatrix":     {"(", ")"},
+	"bmatrix":     {"[", "]"},
+	"Bmatrix":     {"{", "}"},
+	"vmatrix":     {"|", "|"},
+	"Vmatrix":     {"‖", "‖"},
+	"cases":       {"{", ""},
+	"aligned":     {"", ""},
+	"align":       {"", ""},
+	"align*":      {"", ""},
+	"gathered":    {"", ""},
+	"gather":      {"", ""},
+	"gather*":     {"", ""},
+	"split":       {"", ""},
+	"array":       {"", ""},
+	"equation":    {"", ""},
+	"equation*":   {"", ""},
+}
+
+// parseEnvironment parses \begin{env} ... \end{env} into an <mtable>
+func (p *texParser) parseEnvironment() string {
+	env := p.rawGroup()
+	fences, ok := matrixFences[env]
+	if !ok {
+		p.errorf("unsupported environment %s", env)
+	}
+	if env == "array" {
+		p.rawGroup() // Column spec
+	}
+
+	stops := map[string]bool{"&": true, `\\`: true}
+	var rows []string
+	var cells []string
+	for {
+		cell := p.parseRow(stops)
+		cells = append(cells, "<mtd>"+wrapRow(cell)+"</mtd>")
+
+		tok := p.peek()
+		if tok == nil {
+			p.errorf(`missing \end{%s}`, env)
+			break
+		}
+		p.pos++
+		if tok.kind == texAmp {
+			continue
+		}
+		if tok.kind == texCommand && tok.text == `\` {
+			rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+			cells = nil
+			continue
+		}
+		if tok.kind == texCommand && tok.text == "end" {
+			p.rawGroup()
+			break
+		}
+		p.errorf("unexpected %q in %s", tok.text, env)
+	}
+	// Skip a trailing empty row (from a final \\)
+	if len(cells) > 1 || (len(cells) == 1 && cells[0] != "<mtd><mrow></mrow></mtd>") {
+		rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+	}
+
+	table := "<mtable"
+	switch env {
+	case "cases":
+		table += ` columnalign="left left"`
+	case "aligned", "align", "align*", "split":
+		table += ` columnalign="right left right left" columnspacing="0em 2em"`
+	}
+	table += ">" + strings.Join(rows, "") + "</mtable>"
+
+	if fences[0] == "" && fences[1] == "" {
+		return table
+	}
+	result := "<mrow>"
+	if fences[0] != "" {
+		result += `<mo fence="true">` + escapeMathText(fences[0]) + `</mo>`
+	}
+	result += table
+	if fences[1] != "" {
+		result += `<mo fence="true">` + escapeMathText(fences[1]) + `</mo>`
+	}
+	return result + "</mrow>"
+}
+
+// wrapRow joins atoms, wrapping multiple atoms in <mrow>
+func wrapRow(atoms []string) string {
+	if len(atoms) == 1 {
+		return atoms[0]
+	}
+	return "<mrow>" + strings.Join(atoms, "") + "</mrow>"
+}
+
+// escapeMathText escapes text for inclusion in MathML
+func escapeMathText(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	return s
+}
+
+// texSymbol is a symbol command rendered as a single MathML token
+type texSymbol struct {
+	text   string
+	tag    string // mi, mo or mtext
+	limits bool   // Big operators place scripts above/below in display mode
+}
+
+func (s texSymbol) render() string {
+	return "<" + s.tag + ">" + escapeMathText(s.text) + "</" + s.tag + ">"
+}
+
+func mi(text string) texSymbol { return texSymbol{text: text, tag: "mi"} }
+func mo(text string) texSymbol { return texSymbol{text: text, tag: "mo"} }
+func bigOp(text string) texSymbol {
+	return texSymbol{text: text, tag: "mo", limits: true}
+}
+
+// texSymbols maps symbol commands to their Unicode characters
+var texSymbols = map[string]texSymbol{
+	// Greek lowercase
+	"alpha": mi("α"), "beta": mi("β"), "gamma": mi("γ"), "delta": mi("δ"),
+	"epsilon": mi("ϵ"), "varepsilon": mi("ε"), "zeta": mi("ζ"), "eta": mi("η"),
+	"theta": mi("θ"), "vartheta": mi("ϑ"), "iota": mi("ι"), "kappa": mi("κ"),
+	"lambda": mi("λ"), "mu": mi("μ"), "nu": mi("ν"), "xi": mi("ξ"),
+	"pi": mi("π"), "varpi": mi("ϖ"), "rho": mi("ρ"), "varrho": mi("ϱ"),
+	"sigma": mi("σ"), "varsigma": mi("ς"), "tau": mi("τ"), "upsilon": mi("υ"),
+	"phi": mi("ϕ"), "varphi": mi("φ"), "chi": mi("χ"), "psi": mi("ψ"), "omega": mi("ω"),
+	// Greek uppercase
+	"Gamma": mi("Γ"), "Delta": mi("Δ"), "Theta": mi("Θ"), "Lambda": mi("Λ"),
+	"Xi": mi("Ξ"), "Pi": mi("Π"), "Sigma": mi("Σ"), "Upsilon": mi("Υ"),
+	"Phi": mi("Φ"), "Psi": mi("Ψ"), "Omega": mi("Ω"),
+	// Letter-like symbols
+	"infty": mi("∞"), "partial": mi("∂"), "nabla": mi("∇"), "hbar": mi("ℏ"),
+	"ell": mi("ℓ"), "Re": mi("ℜ"), "Im": mi("ℑ"), "aleph": mi("ℵ"),
+	"emptyset": mi("∅"), "varnothing": mi("∅"), "imath": mi("ı"), "jmath": mi("ȷ"),
+	// Big operators
+	"sum": bigOp("∑"), "prod": bigOp("∏"), "coprod": bigOp("∐"),
+	"int": mo("∫"), "iint": mo("∬"), "iiint": mo("∭"), "oint": mo("∮"),
+	"bigcup": bigOp("⋃"), "bigcap": bigOp("⋂"), "bigoplus": bigOp("⨁"),
+	"bigotimes": bigOp("⨂"), "bigvee": bigOp("⋁"), "bigwedge": bigOp("⋀"),
+	// Binary operators
+	"times": mo("×"), "div": mo("÷"), "cdot": mo("⋅"), "pm": mo("±"), "mp": mo("∓"),
+	"ast": mo("∗"), "star": mo("⋆"), "circ": mo("∘"), "bullet": mo("∙"),
+	"oplus": mo("⊕"), "ominus": mo("⊖"), "otimes": mo("⊗"), "oslash": mo("⊘"),
+	"cup": mo("∪"), "cap": mo("∩"), "setminus": mo("∖"), "wedge": mo("∧"),
+	"land": mo("∧"), "vee": mo("∨"), "lor": mo("∨"), "neg": mo("¬"), "lnot": mo("¬"),
+	// Relations
+	"leq": mo("≤"), "le": mo("≤"), "geq": mo("≥"), "ge": mo("≥"), "neq": mo("≠"),
+	"ne": mo("≠"), "approx": mo("≈"), "equiv": mo("≡"), "sim": mo("∼"),
+	"simeq": mo("≃"), "cong": mo("≅"), "propto": mo("∝"), "ll": mo("≪"), "gg": mo("≫"),
+	"in": mo("∈"), "notin": mo("∉"), "ni": mo("∋"), "subset": mo("⊂"), "supset": mo("⊃"),
+	"subseteq": mo("⊆"), "supseteq": mo("⊇"), "mid": mo("∣"), "parallel": mo("∥"),
+	"perp": mo("⊥"), "vdash": mo("⊢"), "models": mo("⊨"), "prec": mo("≺"), "succ": mo("≻"),
+	// Arrows
+	"to": mo("→"), "rightarrow": mo("→"), "leftarrow": mo("←"), "gets": mo("←"),
+	"leftrightarrow": mo("↔"), "Rightarrow": mo("⇒"), "Leftarrow": mo("⇐"),
+	"Leftrightarrow": mo("⇔"), "implies": mo("⟹"), "iff": mo("⟺"), "mapsto": mo("↦"),
+	"longrightarrow": mo("⟶"), "longleftarrow": mo("⟵"), "uparrow": mo("↑"),
+	"downarrow": mo("↓"), "hookrightarrow": mo("↪"),
+	// Quantifiers and logic
+	"forall": mo("∀"), "exists": mo("∃"), "nexists": mo("∄"), "therefore": mo("∴"),
+	"because": mo("∵"),
+	// Dots
+	"ldots": mo("…"), "dots": mo("…"), "cdots": mo("⋯"), "vdots": mo("⋮"), "ddots": mo("⋱"),
+	// Delimiters
+	"{": mo("{"), "}": mo("}"), "langle": mo("⟨"), "rangle": mo("⟩"),
+	"lvert": mo("|"), "rvert": mo("|"), "vert": mo("|"), "|": mo("‖"),
+	"lVert": mo("‖"), "rVert": mo("‖"), "Vert": mo("‖"), "lfloor": mo("⌊"),
+	"rfloor": mo("⌋"), "lceil": mo("⌈"), "rceil": mo("⌉"), "backslash": mo("∖"),
+	// Escaped characters
+	"#": mo("#"), "$": mo("$"), "%": mo("%"), "&": mo("&"), "_": mo("_"),
+	// Misc
+	"angle": mo("∠"), "triangle": mo("△"), "degree": mo("°"), "prime": mo("′"),
+	"top": mo("⊤"), "bot": mo("⊥"), "dagger": mo("†"),
+}
+
+// texFunctions are rendered upright as function names
+var texFunctions = map[string]string{
+	"sin": "sin", "cos": "cos", "tan": "tan", "cot": "cot", "sec": "sec", "csc": "csc",
+	"arcsin": "arcsin", "arccos": "arccos", "arctan": "arctan",
+	"sinh": "sinh", "cosh": "cosh", "tanh": "tanh", "coth": "coth",
+	"log": "log", "ln": "ln", "lg": "lg", "exp": "exp",
+	"lim": "lim", "max": "max", "min": "min", "sup": "sup", "inf": "inf",
+	"det": "det", "dim": "dim", "ker": "ker", "gcd": "gcd", "deg": "deg",
+	"arg": "arg", "hom": "hom", "Pr": "Pr", "mod": "mod", "bmod": "mod",
+}
+
+// texLimitFunctions take their subscripts underneath in display mode (\lim_{x \to 0})
+var texLimitFunctions = map[string]bool{
+	"lim": true, "max": true, "min": true, "sup": true, "inf": true, "det": true, "gcd": true, "Pr": true,
+}
+
+// texSpaces maps spacing commands to widths
+var texSpaces = map[string]string{
+	",": "0.1667em", "thinspace": "0.1667em",
+	":": "0.2222em", ">": "0.2222em", "medspace": "0.2222em",
+	";": "0.2778em", "thickspace": "0.2778em",
+	" ":    "0.333em",
+	"quad": "1em", "qquad": "2em",
+	"!": "-0.1667em", "negthinspace": "-0.1667em",
+}
+
+// texAccent is an accent placed over (or under) its argument
+type texAccent struct {
+	mark     string
+	stretchy bool
+	under    bool
+}
+
+var texAccents = map[string]texAccent{
+	"hat": {mark: "^"}, "widehat": {mark: "^", stretchy: true},
+	"tilde": {mark: "~"}, "widetilde": {mark: "~", stretchy: true},
+	"bar": {mark: "‾"}, "overline": {mark: "‾", stretchy: true},
+	"vec": {mark: "→"}, "overrightarrow": {mark: "→", stretchy: true},
+	"overleftarrow": {mark: "←", stretchy: true},
+	"dot":           {mark: "˙"}, "ddot": {mark: "¨"}, "acute": {mark: "´"},
+	"grave": {mark: "`"}, "breve": {mark: "˘"}, "check": {mark: "ˇ"},
+	"overbrace":  {mark: "⏞", stretchy: true},
+	"underbrace": {mark: "⏟", stretchy: true, under: true},
+	"underline":  {mark: "_", stretchy: true, under: true},
+}
+
+// texFonts maps font commands to Unicode math alphabets
+var texFonts = map[string]string{
+	"mathbf":       "bold",
+	"boldsymbol":   "bold",
+	"bm":           "bold",
+	"mathit":       "italic",
+	"mathbb":       "double-struck",
+	"mathcal":      "script",
+	"mathscr":      "script",
+	"mathfrak":     "fraktur",
+	"mathsf":       "sans-serif",
+	"mathtt":       "monospace",
+	"mathrm":       "normal",
+	"mathnormal":   "normal",
+	"textsf":       "sans-serif",
+	"texttt":       "monospace",
+	"pmb":          "bold",
+	"operatorname": "normal",
+}
+
+// mathAlphabets holds the Unicode start points for A, a and 0 in each alphabet
+var mathAlphabets = map[string][3]rune{
+	"bold":          {0x1D400, 0x1D41A, 0x1D7CE},
+	"italic":        {0x1D434, 0x1D44E, 0},
+	"script":        {0x1D49C, 0x1D4B6, 0},
+	"fraktur":       {0x1D504, 0x1D51E, 0},
+	"double-struck": {0x1D538, 0x1D552, 0x1D7D8},
+	"sans-serif":    {0x1D5A0, 0x1D5BA, 0x1D7E2},
+	"monospace":     {0x1D670, 0x1D68A, 0x1D7F6},
+}
+
+// mathAlphabetHoles are letters that live in the Letterlike Symbols block instead
+var mathAlphabetHoles = map[string]map[rune]rune{
+	"italic":        {'h': 'ℎ'},
+	"script":        {'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ', 'M': 'ℳ', 'R': 'ℛ', 'e': 'ℯ', 'g': 'ℊ', 'o': 'ℴ'},
+	"fraktur":       {'C': 'ℭ', 'H': 'ℌ', 'I': 'ℑ', 'R': 'ℜ', 'Z': 'ℨ'},
+	"double-struck": {'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ'},
+}
+
+// mathAlphanumeric renders text in a math alphabet using Unicode math symbols,
+// which (unlike mathvariant) are supported by MathML Core
+func mathAlphanumeric(text, variant string) string {
+	if variant == "normal" {
+		return `<mi mathvariant="normal">` + escapeMathText(text) + `</mi>`
+	}
+
+	starts := mathAlphabets[variant]
+	var sb strings.Builder
+	for _, c := range text {
+		if hole, ok := mathAlphabetHoles[variant][c]; ok {
+			sb.WriteRune(hole)
+			continue
+		}
+		switch {
+		case c >= 'A' && c <= 'Z':
+			sb.WriteRune(starts[0] + c - 'A')
+		case c >= 'a' && c <= 'z':
+			sb.WriteRune(starts[1] + c - 'a')
+		case c >= '0' && c <= '9' && starts[2] != 0:
+			sb.WriteRune(starts[2] + c - '0')
+		default:
+			sb.WriteRune(c)
+		}
+	}
+
+	tag := "mi"
+	if len([]rune(text)) > 0 && unicode.IsDigit([]rune(text)[0]) {
+		tag = "mn"
+	}
+	return "<" + tag + ">" + escapeMathText(sb.String()) + "</" + tag + ">"
+}
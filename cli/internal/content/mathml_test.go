@@ -0,0 +1,174 @@
+package content
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mathBody returns the MathML between <semantics> and the TeX annotation
+func mathBody(t *testing.T, out string) string {
+	t.Helper()
+	start := strings.Index(out, "<semantics>")
+	end := strings.Index(out, "<annotation")
+	if start < 0 || end < start {
+		t.Fatalf("unexpected MathML wrapper: %s", out)
+	}
+	return out[start+len("<semantics>") : end]
+}
+
+// checkWellFormed fails if out isn't a single well-formed XML element
+func checkWellFormed(t *testing.T, tex, out string) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(out))
+	depth := 0
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%q: malformed MathML: %v\n%s", tex, err, out)
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if depth != 0 {
+		t.Fatalf("%q: unbalanced MathML:\n%s", tex, out)
+	}
+}
+
+func TestTeXToMathML(t *testing.T) {
+	tests := []struct {
+		name string
+		tex  string
+		want string
+	}{
+		{"identifier", `x`, `<mi>x</mi>`},
+		{"number", `3.14`, `<mn>3.14</mn>`},
+		{"operators", `a<b`, `<mrow><mi>a</mi><mo>&lt;</mo><mi>b</mi></mrow>`},
+		{"minus", `a-b`, `<mrow><mi>a</mi><mo>−</mo><mi>b</mi></mrow>`},
+		{"fraction", `\frac{a}{b}`, `<mfrac><mi>a</mi><mi>b</mi></mfrac>`},
+		{"nested fraction", `\frac{1}{\frac{x}{2}}`, `<mfrac><mn>1</mn><mfrac><mi>x</mi><mn>2</mn></mfrac></mfrac>`},
+		{"superscript", `x^2`, `<msup><mi>x</mi><mn>2</mn></msup>`},
+		{"single digit script", `x^23`, `<mrow><msup><mi>x</mi><mn>2</mn></msup><mn>3</mn></mrow>`},
+		{"grouped script", `x^{23}`, `<msup><mi>x</mi><mn>23</mn></msup>`},
+		{"subscript", `a_i`, `<msub><mi>a</mi><mi>i</mi></msub>`},
+		{"sub and superscript", `x_i^2`, `<msubsup><mi>x</mi><mi>i</mi><mn>2</mn></msubsup>`},
+		{"prime", `f'`, `<msup><mi>f</mi><mo>′</mo></msup>`},
+		{"square root", `\sqrt{x}`, `<msqrt><mi>x</mi></msqrt>`},
+		{"nth root", `\sqrt[3]{x}`, `<mroot><mi>x</mi><mn>3</mn></mroot>`},
+		{"greek", `\alpha+\beta`, `<mrow><mi>α</mi><mo>+</mo><mi>β</mi></mrow>`},
+		{"big operator", `\sum_{i=0}^n i`, `<msubsup><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>0</mn></mrow><mi>n</mi></msubsup>`},
+		{"function", `\sin x`, `<mi>sin</mi>`},
+		{"text", `\text{if } x`, `<mrow><mtext>if </mtext><mi>x</mi></mrow>`},
+		{"text is escaped", `\text{<b>&}`, `<mtext>&lt;b&gt;&amp;</mtext>`},
+		{"font", `\mathbb{R}`, `<mi>ℝ</mi>`},
+		{"accent", `\hat{x}`, `<mover accent="true"><mi>x</mi><mo stretchy="false">^</mo></mover>`},
+		{"left right", `\left( x \right)`, `<mrow><mo fence="true" stretchy="true">(</mo><mi>x</mi><mo fence="true" stretchy="true">)</mo></mrow>`},
+		{"matrix", `\begin{pmatrix}a&b\\c&d\end{pmatrix}`, `<mrow><mo fence="true">(</mo><mtable><mtr><mtd><mi>a</mi></mtd><mtd><mi>b</mi></mtd></mtr><mtr><mtd><mi>c</mi></mtd><mtd><mi>d</mi></mtd></mtr></mtable><mo fence="true">)</mo></mrow>`},
+		{"plain matrix", `\begin{matrix}1&0\end{matrix}`, `<mtable><mtr><mtd><mn>1</mn></mtd><mtd><mn>0</mn></mtd></mtr></mtable>`},
+		{"aligned", `\begin{aligned}a&=b\\c&=d\end{aligned}`, `<mtable columnalign="right left right left"`},
+		{"empty", ``, `<mrow></mrow>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := TeXToMathML(tt.tex, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if body := mathBody(t, out); !strings.Contains(body, tt.want) {
+				t.Errorf("TeXToMathML(%q) =\n%s\nwant it to contain\n%s", tt.tex, body, tt.want)
+			}
+			checkWellFormed(t, tt.tex, out)
+		})
+	}
+}
+
+func TestTeXToMathMLDisplay(t *testing.T) {
+	out, err := TeXToMathML(`\lim_{x \to 0} x`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, ` display="block"`) {
+		t.Errorf("display math isn't a block:\n%s", out)
+	}
+	if !strings.Contains(out, `<munder><mi>lim</mi>`) {
+		t.Errorf("display limits aren't placed underneath:\n%s", out)
+	}
+
+	inline, _ := TeXToMathML(`\lim_{x \to 0} x`, false)
+	if strings.Contains(inline, "display=") || !strings.Contains(inline, `<msub><mi>lim</mi>`) {
+		t.Errorf("inline limits should be a subscript:\n%s", inline)
+	}
+}
+
+func TestTeXToMathMLAnnotation(t *testing.T) {
+	out, _ := TeXToMathML(`  a < b  `, false)
+	if !strings.Contains(out, `<annotation encoding="application/x-tex">a &lt; b</annotation>`) {
+		t.Errorf("annotation doesn't hold the escaped TeX:\n%s", out)
+	}
+}
+
+func TestTeXToMathMLErrors(t *testing.T) {
+	tests := []struct {
+		tex     string
+		wantErr string
+	}{
+		{`\foo`, `unsupported command \foo`},
+		{`{a`, `missing closing brace`},
+		{`a}`, `unexpected "}"`},
+		{`x\`, `trailing \`},
+		{`\frac{a}`, `missing argument`},
+		{`\sqrt`, `missing argument`},
+		{`x^`, `missing argument`},
+		{`^`, `missing argument`},
+		{`\left( x`, `\left without \right`},
+		{`\begin{pmatrix}a`, `missing \end{pmatrix}`},
+		{`\end{pmatrix}`, `unexpected "end"`},
+		{`\begin{foo}a\end{foo}`, `unsupported environment foo`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tex, func(t *testing.T) {
+			out, err := TeXToMathML(tt.tex, false)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+			// Malformed input still renders something usable
+			checkWellFormed(t, tt.tex, out)
+		})
+	}
+
+	if out, _ := TeXToMathML(`\foo`, false); !strings.Contains(out, `<merror><mtext>\foo</mtext></merror>`) {
+		t.Errorf("unknown command isn't shown as an error:\n%s", out)
+	}
+}
+
+func FuzzTeXToMathML(f *testing.F) {
+	for _, seed := range []string{
+		`\frac{a}{b}`, `x_i^2`, `\sqrt[3]{x}`, `\begin{pmatrix}a&b\\c&d\end{pmatrix}`,
+		`\text{a}`, `\left( x \right)`, `{`, `}`, `\`, `^^`, `__`, `\begin{`, `\end{`,
+		`\sqrt[`, `\frac`, `\left`, `\right)`, `&&\\`, `\text{`, `\mathbb{`, `x''''`,
+	} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+	f.Fuzz(func(t *testing.T, tex string, display bool) {
+		out, _ := TeXToMathML(tex, display)
+		if !strings.HasPrefix(out, "<math") || !strings.HasSuffix(out, "</math>") {
+			t.Fatalf("%q: output isn't a <math> element:\n%s", tex, out)
+		}
+		if utf8.ValidString(tex) && !strings.ContainsFunc(tex, unicode.IsControl) {
+			checkWellFormed(t, tex, out)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package content
 import (
 	"fmt"
 	"html"
+	"path"
 	"regexp"
 	"strings"
 	"time"
@@ -13,17 +14,21 @@ var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
 // Page represents a content page
 type Page struct {
 	// Metadata from frontmatter
-	Title       string
-	Description string    // SEO meta description (from frontmatter or auto-generated)
-	Date        time.Time // Primary display date (from date, created, or createdAt)
-	Created     time.Time // Creation date (from created, createdAt, or date)
-	Modified    time.Time // Last modified date (from modified, updated, or updatedAt)
-	Tags        []string
-	Draft       bool
-	Growth      string // seedling | budding | evergreen
-	TOC         *bool  // Override site-wide TOC setting (nil = use site default)
-	ShowList    *bool  // Show page list on section index (nil = true)
-	Image       string // OG image for this page (from frontmatter)
+	Title        string
+	Description  string    // SEO meta description (from frontmatter or auto-generated)
+	Date         time.Time // Primary display date (from date, created, or createdAt)
+	Created      time.Time // Creation date (from created, createdAt, or date)
+	Modified     time.Time // Last modified date (from modified, updated, or updatedAt)
+	Tags         []string
+	Draft        bool
+	Publish      *bool          // Publish in optIn mode (nil = only inside a publish folder)
+	Growth       string         // seedling | budding | evergreen
+	TOC          *bool          // Override site-wide TOC setting (nil = use site default)
+	ShowList     *bool          // Show page list on section index (nil = true)
+	Image        string         // OG image for this page (from frontmatter)
+	Aliases      []string       // Alternative names for wiki-links (from frontmatter)
+	RedirectFrom []string       // Old slugs that redirect here (from frontmatter)
+	Params       map[string]any // Every frontmatter field, including custom ones
 
 	// Paths
 	SourcePath string // Relative path to .md file (e.g., "projects/leafpress.md")
@@ -32,8 +37,14 @@ type Page struct {
 	Permalink  string // Full URL path (e.g., "/projects/leafpress/")
 
 	// Content
-	RawContent  string // Original markdown (without frontmatter)
-	HTMLContent string // Rendered HTML
+	RawContent  string   // Original markdown (without frontmatter)
+	HTMLContent string   // Rendered HTML
+	Warnings    []string // Warnings from the last render (broken links, bad queries, ...)
+
+	// Source lines in the .md file (1-based, 0 = unknown), set by rendering
+	WarningLines []int            // Line of each warning in Warnings
+	RefLines     map[string][]int // Lines of each href and src URL in HTMLContent, in order
+	sourceLines  lineMap          // RawContent line -> .md file line (0-based)
 
 	// Relationships
 	Backlinks []*Page  // Pages that link to this page
@@ -50,6 +61,102 @@ type Page struct {
 	SectionSort string // Sort order for section pages (date|title|growth)
 }
 
+// RedirectSlugs returns the slugs that should redirect to this page:
+// each alias as a sibling of the page, plus every redirect_from entry as given
+func (p *Page) RedirectSlugs() []string {
+	var slugs []string
+	seen := map[string]bool{p.Slug: true}
+
+	add := func(slug string) {
+		if slug != "" && !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+
+	dir := path.Dir(p.Slug)
+	for _, alias := range p.Aliases {
+		slug := aliasSlug(alias)
+		if slug != "" && dir != "." && dir != "" && !p.IsIndex {
+			slug = dir + "/" + slug
+		}
+		add(slug)
+	}
+	for _, from := range p.RedirectFrom {
+		if from = strings.TrimSuffix(strings.TrimSpace(from), ".md"); validURLPath(from) {
+			add(strings.Trim(from, "/"))
+		}
+	}
+
+	return slugs
+}
+
+// Param returns a frontmatter field by name, ignoring case if there's no exact match
+func (p *Page) Param(key string) any {
+	if value, ok := p.Params[key]; ok {
+		return value
+	}
+	for k, value := range p.Params {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return nil
+}
+
+// ParamString returns a frontmatter field formatted for display
+// (lists are joined with commas, dates use the page date format)
+func (p *Page) ParamString(key string) string {
+	return formatParam(p.Param(key))
+}
+
+func formatParam(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format("Jan 2, 2006")
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := formatParam(item); s != "" {
+				items = append(items, s)
+			}
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+// CustomParams returns the frontmatter fields leafpress doesn't interpret itself
+func (p *Page) CustomParams() map[string]any {
+	var custom map[string]any
+	for key, value := range p.Params {
+		if builtinParams[key] {
+			continue
+		}
+		if custom == nil {
+			custom = make(map[string]any)
+		}
+		custom[key] = value
+	}
+	return custom
+}
+
+// aliasSlug converts an alias into a URL path segment ("My Alias" -> "my-alias")
+func aliasSlug(alias string) string {
+	slug := strings.ToLower(strings.TrimSpace(alias))
+	slug = strings.Join(strings.Fields(slug), "-")
+	slug = strings.NewReplacer("/", "-", "?", "", "#", "").Replace(slug)
+	slug = strings.Trim(slug, "-")
+	if slug == "." || slug == ".." {
+		return ""
+	}
+	return slug
+}
+
 // GrowthEmoji returns the emoji for the growth stage
 func (p *Page) GrowthEmoji() string {
 	switch p.Growth {
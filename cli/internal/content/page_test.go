@@ -0,0 +1,82 @@
+package content
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedirectSlugs(t *testing.T) {
+	tests := []struct {
+		name  string
+		page  Page
+		slugs []string
+	}{
+		{
+			name:  "aliases are siblings of the page",
+			page:  Page{Slug: "notes/garden", Aliases: []string{"My Garden", "Old Name"}},
+			slugs: []string{"notes/my-garden", "notes/old-name"},
+		},
+		{
+			name:  "aliases of a section index stay at the root",
+			page:  Page{Slug: "notes", IsIndex: true, Aliases: []string{"Notebook"}},
+			slugs: []string{"notebook"},
+		},
+		{
+			name:  "redirect_from is used as given",
+			page:  Page{Slug: "garden", RedirectFrom: []string{"/old/garden/", "older.md"}},
+			slugs: []string{"old/garden", "older"},
+		},
+		{
+			name:  "duplicates and the page's own slug are dropped",
+			page:  Page{Slug: "garden", Aliases: []string{"Garden", "Yard"}, RedirectFrom: []string{"yard"}},
+			slugs: []string{"yard"},
+		},
+		{
+			name:  "dot segments are dropped",
+			page:  Page{Slug: "garden", Aliases: []string{"..", "."}, RedirectFrom: []string{"../../x", "a/./b", "a//b", "/"}},
+			slugs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.page.RedirectSlugs(); !reflect.DeepEqual(got, tt.slugs) {
+				t.Errorf("RedirectSlugs() = %q, want %q", got, tt.slugs)
+			}
+		})
+	}
+}
+
+func TestRedirectFromValidation(t *testing.T) {
+	tests := []struct {
+		from  string
+		valid bool
+	}{
+		{"old/garden", true},
+		{"/old/garden/", true},
+		{"garden.md", true},
+		{"../../x", false},
+		{"old/../garden", false},
+		{"./garden", false},
+		{"old//garden", false},
+		{"/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			_, _, err := ParseFrontmatter("---\ntitle: Garden\nredirect_from:\n  - \"" + tt.from + "\"\n---\nBody\n")
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid {
+				fmErr, ok := err.(*FrontmatterError)
+				if !ok {
+					t.Fatalf("error = %v, want a FrontmatterError", err)
+				}
+				if fmErr.Line != 2 {
+					t.Errorf("error line = %d, want 2", fmErr.Line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,509 @@
+package content
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// QueryLanguage is the info string of fenced code blocks holding a page query
+const QueryLanguage = "leafpress-query"
+
+// Query is a parsed leafpress-query block, e.g.
+// "tag: reading AND growth: evergreen SORT modified DESC LIMIT 10"
+type Query struct {
+	filter queryExpr // nil matches every page
+	SortBy string    // title | date | created | modified | growth
+	Desc   bool
+	Limit  int  // 0 = no limit
+	Table  bool // Render as a table instead of a list
+}
+
+// queryExpr is a node of a query's filter
+type queryExpr interface {
+	match(p *Page) bool
+}
+
+type andExpr []queryExpr
+type orExpr []queryExpr
+type notExpr struct{ expr queryExpr }
+
+// queryCondition compares one page field to a value ("growth: evergreen", "date >= 2024-01")
+type queryCondition struct {
+	field string
+	op    string
+	value string
+}
+
+func (e andExpr) match(p *Page) bool {
+	for _, expr := range e {
+		if !expr.match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e orExpr) match(p *Page) bool {
+	for _, expr := range e {
+		if expr.match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e notExpr) match(p *Page) bool {
+	return !e.expr.match(p)
+}
+
+func (c queryCondition) match(p *Page) bool {
+	switch c.field {
+	case "tag":
+		// Nested tags match their parents: "tag: project" includes project/leafpress
+		for _, tag := range p.Tags {
+			tag = NormalizeTag(tag)
+			if tag == c.value || strings.HasPrefix(tag, c.value+"/") {
+				return c.op != "!="
+			}
+		}
+		return c.op == "!="
+	case "folder":
+		dir := path.Dir(p.Slug)
+		if p.IsIndex {
+			dir = p.Slug
+		}
+		dir = strings.ToLower(dir)
+		in := dir == c.value || strings.HasPrefix(dir, c.value+"/")
+		return in == (c.op != "!=")
+	case "growth":
+		return (strings.ToLower(p.Growth) == c.value) == (c.op != "!=")
+	default:
+		return c.matchDate(queryDate(p, c.field))
+	}
+}
+
+// matchDate compares an ISO date against the condition's (possibly partial) date.
+// ISO dates sort as strings, and "date: 2024-05" matches any day in May 2024.
+func (c queryCondition) matchDate(date string) bool {
+	if date == "" {
+		return false
+	}
+	switch c.op {
+	case ":", "=":
+		return strings.HasPrefix(date, c.value)
+	case "!=":
+		return !strings.HasPrefix(date, c.value)
+	case ">":
+		return date > c.value && !strings.HasPrefix(date, c.value)
+	case ">=":
+		return date >= c.value
+	case "<":
+		return date < c.value
+	case "<=":
+		return date <= c.value || strings.HasPrefix(date, c.value)
+	}
+	return false
+}
+
+// queryDate returns a page's created, modified or display date in ISO format
+func queryDate(p *Page, field string) string {
+	date := p.Date
+	switch field {
+	case "created":
+		date = p.Created
+	case "modified":
+		// Pages without a modified date were last changed when created
+		if !p.Modified.IsZero() {
+			date = p.Modified
+		}
+	}
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+var (
+	// queryTokenRegex splits a query into parentheses, operators, quoted strings and words
+	queryTokenRegex = regexp.MustCompile(`\(|\)|>=|<=|!=|[:=<>]|"[^"]*"|[^\s()":=<>!]+`)
+	// queryDateRegex matches the full or partial ISO dates queries compare against
+	queryDateRegex = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+)
+
+var queryFields = map[string]bool{"tag": true, "folder": true, "growth": true, "date": true, "created": true, "modified": true}
+var querySortFields = map[string]bool{"title": true, "date": true, "created": true, "modified": true, "growth": true}
+
+// queryParser is a recursive descent parser over query tokens
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+// ParseQuery parses the body of a leafpress-query block
+func ParseQuery(source string) (*Query, error) {
+	p := &queryParser{tokens: queryTokenRegex.FindAllString(source, -1)}
+	q := &Query{SortBy: "date", Desc: true}
+
+	if p.peek() != "" && !p.isKeyword("SORT", "LIMIT", "AS") {
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		q.filter = filter
+	}
+
+	if p.isKeyword("SORT") {
+		p.pos++
+		field := strings.ToLower(p.next())
+		if !querySortFields[field] {
+			return nil, fmt.Errorf("cannot sort by %q", field)
+		}
+		q.SortBy, q.Desc = field, false
+		if p.isKeyword("ASC", "DESC") {
+			q.Desc = strings.EqualFold(p.next(), "DESC")
+		}
+	}
+
+	if p.isKeyword("LIMIT") {
+		p.pos++
+		limit, err := strconv.Atoi(p.next())
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("LIMIT must be a positive number")
+		}
+		q.Limit = limit
+	}
+
+	if p.isKeyword("AS") {
+		p.pos++
+		switch strings.ToUpper(p.next()) {
+		case "LIST":
+		case "TABLE":
+			q.Table = true
+		default:
+			return nil, fmt.Errorf("AS must be followed by LIST or TABLE")
+		}
+	}
+
+	if token := p.peek(); token != "" {
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+	return q, nil
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *queryParser) next() string {
+	token := p.peek()
+	if token != "" {
+		p.pos++
+	}
+	return token
+}
+
+// isKeyword reports whether the next token is one of the keywords (case-insensitive)
+func (p *queryParser) isKeyword(keywords ...string) bool {
+	token := p.peek()
+	for _, keyword := range keywords {
+		if strings.EqualFold(token, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOr parses conditions joined by OR; AND binds tighter
+func (p *queryParser) parseOr() (queryExpr, error) {
+	var exprs orExpr
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.isKeyword("OR") {
+			break
+		}
+		p.pos++
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *queryParser) parseAnd() (queryExpr, error) {
+	var exprs andExpr
+	for {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.isKeyword("AND") {
+			break
+		}
+		p.pos++
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *queryParser) parseNot() (queryExpr, error) {
+	if p.isKeyword("NOT") {
+		p.pos++
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+
+	if p.peek() == "(" {
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return expr, nil
+	}
+
+	return p.parseCondition()
+}
+
+func (p *queryParser) parseCondition() (queryExpr, error) {
+	field := strings.ToLower(p.next())
+	if !queryFields[field] {
+		if field == "" {
+			return nil, fmt.Errorf("expected a condition")
+		}
+		return nil, fmt.Errorf("unknown field %q (use tag, folder, growth, date, created or modified)", field)
+	}
+
+	op := p.next()
+	value := strings.ToLower(strings.Trim(p.next(), `"`))
+	switch {
+	case op == "" || strings.Trim(op, ":=<>!") != "":
+		return nil, fmt.Errorf("expected an operator after %s", field)
+	case value == "":
+		return nil, fmt.Errorf("expected a value after %s%s", field, op)
+	}
+
+	switch field {
+	case "tag", "folder":
+		value = strings.Trim(strings.TrimPrefix(value, "#"), "/")
+		if field == "tag" {
+			value = NormalizeTag(value)
+		}
+		fallthrough
+	case "growth":
+		if op != ":" && op != "=" && op != "!=" {
+			return nil, fmt.Errorf("%s only supports :, = and !=", field)
+		}
+	default:
+		if !queryDateRegex.MatchString(value) {
+			return nil, fmt.Errorf("%s must be compared to a date like 2024, 2024-05 or 2024-05-01", field)
+		}
+	}
+
+	return queryCondition{field: field, op: op, value: value}, nil
+}
+
+// Run returns the pages matching the query, sorted and limited, leaving out exclude
+func (q *Query) Run(pages []*Page, exclude *Page) []*Page {
+	var results []*Page
+	for _, p := range pages {
+		if p == exclude || (q.filter != nil && !q.filter.match(p)) {
+			continue
+		}
+		results = append(results, p)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		var less, equal bool
+		switch q.SortBy {
+		case "title":
+			ta, tb := strings.ToLower(a.Title), strings.ToLower(b.Title)
+			less, equal = ta < tb, ta == tb
+		case "growth":
+			ga, gb := growthRank(a.Growth), growthRank(b.Growth)
+			less, equal = ga < gb, ga == gb
+		default:
+			da, db := queryDate(a, q.SortBy), queryDate(b, q.SortBy)
+			less, equal = da < db, da == db
+		}
+		if equal {
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		}
+		return less != q.Desc
+	})
+
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
+
+// Matches reports whether a page passes the query's filter
+func (q *Query) Matches(p *Page) bool {
+	return q.filter == nil || q.filter.match(p)
+}
+
+// growthRank orders growth stages from seedling to evergreen
+func growthRank(growth string) int {
+	switch growth {
+	case "seedling":
+		return 1
+	case "budding":
+		return 2
+	case "evergreen":
+		return 3
+	}
+	return 0
+}
+
+// queryBlockRegex matches fenced leafpress-query blocks
+var queryBlockRegex = regexp.MustCompile("(?m)^[ \t]*(?:```|~~~)[ \t]*" + QueryLanguage + "[ \t]*\n((?s:.*?))^[ \t]*(?:```|~~~)")
+
+// ExtractQueries returns the queries in a page's markdown, skipping ones that don't parse
+func ExtractQueries(content string) []*Query {
+	var queries []*Query
+	for _, match := range queryBlockRegex.FindAllStringSubmatch(content, -1) {
+		if q, err := ParseQuery(match[1]); err == nil {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// KindQuery is the NodeKind for evaluated query blocks
+var KindQuery = ast.NewNodeKind("Query")
+
+// QueryNode is a leafpress-query block in the markdown AST, evaluated while
+// parsing, so the node only holds the finished HTML
+type QueryNode struct {
+	ast.BaseBlock
+	HTML string
+}
+
+// Kind implements ast.Node.Kind
+func (n *QueryNode) Kind() ast.NodeKind { return KindQuery }
+
+// Dump implements ast.Node.Dump
+func (n *QueryNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+func renderQuery(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString(node.(*QueryNode).HTML)
+		w.WriteByte('\n')
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// queryTransformer replaces leafpress-query code blocks with their results
+type queryTransformer struct {
+	renderer *Renderer
+}
+
+func (t *queryTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if n, ok := node.(*ast.FencedCodeBlock); ok && entering && string(n.Language(source)) == QueryLanguage {
+			blocks = append(blocks, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	state := getRenderState(pc)
+	for _, block := range blocks {
+		var body strings.Builder
+		for i := 0; i < block.Lines().Len(); i++ {
+			line := block.Lines().At(i)
+			body.Write(line.Value(source))
+		}
+		offset := -1
+		if block.Lines().Len() > 0 {
+			offset = block.Lines().At(0).Start
+		}
+		from := len(state.warnings)
+		node := &QueryNode{HTML: t.renderer.renderQuery(body.String(), state)}
+		state.locate(from, offset)
+		state.setNodeOffset(node, offset)
+		block.Parent().ReplaceChild(block.Parent(), block, node)
+	}
+}
+
+// renderQuery evaluates a query against all pages and renders the results
+func (r *Renderer) renderQuery(source string, state *renderState) string {
+	q, err := ParseQuery(source)
+	if err != nil {
+		where := ""
+		if len(state.stack) > 0 {
+			where = " in " + state.stack[0].SourcePath
+		}
+		state.warnings = append(state.warnings, "invalid query"+where+": "+err.Error())
+		return `<div class="lp-query lp-query-error">Invalid query: ` + html.EscapeString(err.Error()) + `</div>`
+	}
+
+	var pages []*Page
+	if r.resolver != nil {
+		pages = q.Run(r.resolver.pages, state.current())
+	}
+	if len(pages) == 0 {
+		return `<p class="lp-query lp-query-empty">No matching pages.</p>`
+	}
+
+	var b strings.Builder
+	if q.Table {
+		b.WriteString("<table class=\"lp-query\">\n<thead>\n<tr><th>Page</th><th>Growth</th><th>Tags</th><th>Date</th></tr>\n</thead>\n<tbody>\n")
+		for _, p := range pages {
+			fmt.Fprintf(&b, "<tr><td><a class=\"lp-query-link\" href=\"%s\">%s</a></td><td>%s</td><td>%s</td><td>",
+				html.EscapeString(r.basePath+p.Permalink), html.EscapeString(p.Title), p.GrowthEmoji(), html.EscapeString(strings.Join(p.Tags, ", ")))
+			if date := p.DisplayDate(); date != "" {
+				fmt.Fprintf(&b, "<time datetime=\"%s\">%s</time>", p.DisplayDateISO(), date)
+			}
+			b.WriteString("</td></tr>\n")
+		}
+		b.WriteString("</tbody>\n</table>")
+		return b.String()
+	}
+
+	b.WriteString("<ul class=\"lp-query lp-index\">\n")
+	for _, p := range pages {
+		fmt.Fprintf(&b, "<li class=\"lp-index-item\"><a class=\"lp-index-link\" href=\"%s\">", html.EscapeString(r.basePath+p.Permalink))
+		if p.Growth != "" {
+			fmt.Fprintf(&b, "<span class=\"lp-index-growth lp-index-growth--%s\">%s</span>", html.EscapeString(p.Growth), p.GrowthEmoji())
+		}
+		fmt.Fprintf(&b, "<span class=\"lp-index-title\">%s</span></a>", html.EscapeString(p.Title))
+		if date := p.DisplayDate(); date != "" {
+			fmt.Fprintf(&b, "<time class=\"lp-index-date\" datetime=\"%s\">%s</time>", p.DisplayDateISO(), date)
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package content
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		source string
+		sortBy string
+		desc   bool
+		limit  int
+		table  bool
+	}{
+		{"", "date", true, 0, false},
+		{"tag: reading", "date", true, 0, false},
+		{"SORT title", "title", false, 0, false},
+		{"growth: evergreen SORT modified DESC LIMIT 10", "modified", true, 10, false},
+		{"tag: a OR (tag: b AND NOT folder: drafts) sort growth asc limit 3 as table", "growth", false, 3, true},
+		{"date >= 2024-05 AS LIST", "date", true, 0, false},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.source)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.source, err)
+			continue
+		}
+		if q.SortBy != tt.sortBy || q.Desc != tt.desc || q.Limit != tt.limit || q.Table != tt.table {
+			t.Errorf("ParseQuery(%q) = {%s %v %d %v}, want {%s %v %d %v}", tt.source,
+				q.SortBy, q.Desc, q.Limit, q.Table, tt.sortBy, tt.desc, tt.limit, tt.table)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"title: foo", `unknown field "title"`},
+		{"tag", "expected an operator after tag"},
+		{"tag:", "expected a value after tag:"},
+		{"tag > a", "tag only supports :, = and !="},
+		{"date: May", "date must be compared to a date"},
+		{"(tag: a", "missing )"},
+		{"tag: a AND", "expected a condition"},
+		{"SORT size", `cannot sort by "size"`},
+		{"LIMIT 0", "LIMIT must be a positive number"},
+		{"AS GRID", "AS must be followed by LIST or TABLE"},
+		{"tag: a tag: b", `unexpected "tag"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.source)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseQuery(%q) error = %v, want %q", tt.source, err, tt.want)
+		}
+	}
+}
+
+// queryPages returns a few pages covering the fields queries filter and sort on
+func queryPages() []*Page {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	return []*Page{
+		{Title: "Alpha", Slug: "notes/alpha", Tags: []string{"reading"}, Growth: "seedling", Date: day("2024-05-01")},
+		{Title: "Beta", Slug: "notes/beta", Tags: []string{"reading/books"}, Growth: "evergreen", Date: day("2024-06-10"), Modified: day("2024-07-01")},
+		{Title: "Gamma", Slug: "drafts/gamma", Tags: []string{"ideas"}, Growth: "budding", Date: day("2023-12-31")},
+		{Title: "Delta", Slug: "notes", IsIndex: true, Date: day("2024-05-20")},
+	}
+}
+
+func pageTitles(pages []*Page) []string {
+	titles := []string{}
+	for _, p := range pages {
+		titles = append(titles, p.Title)
+	}
+	return titles
+}
+
+func TestQueryRun(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{"", []string{"Beta", "Delta", "Alpha", "Gamma"}},
+		{"tag: reading SORT title", []string{"Alpha", "Beta"}},
+		{"tag: reading/books", []string{"Beta"}},
+		{"tag != reading SORT title", []string{"Delta", "Gamma"}},
+		{"folder: notes SORT title", []string{"Alpha", "Beta", "Delta"}},
+		{"NOT folder: notes", []string{"Gamma"}},
+		{"growth: evergreen OR growth: budding SORT title", []string{"Beta", "Gamma"}},
+		{"date: 2024-05 SORT date ASC", []string{"Alpha", "Delta"}},
+		{"date > 2024-05", []string{"Beta"}},
+		{"date <= 2024-05 SORT title", []string{"Alpha", "Delta", "Gamma"}},
+		{"modified >= 2024-06", []string{"Beta"}},
+		{"SORT growth DESC", []string{"Beta", "Gamma", "Alpha", "Delta"}},
+		{"SORT title LIMIT 2", []string{"Alpha", "Beta"}},
+		{"(tag: ideas OR tag: reading) AND growth != seedling SORT title", []string{"Beta", "Gamma"}},
+	}
+	pages := queryPages()
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.source)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.source, err)
+		}
+		if got := pageTitles(q.Run(pages, nil)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestQueryRunExcludesCurrentPage(t *testing.T) {
+	pages := queryPages()
+	q, _ := ParseQuery("SORT title")
+	if got, want := pageTitles(q.Run(pages, pages[0])), []string{"Beta", "Delta", "Gamma"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Run() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractQueries(t *testing.T) {
+	source := "Intro\n\n```leafpress-query\ntag: reading\n```\n\n~~~leafpress-query\nSORT size\n~~~\n\n```go\ntag: ideas\n```\n"
+	queries := ExtractQueries(source)
+	if len(queries) != 1 {
+		t.Fatalf("ExtractQueries() found %d queries, want 1", len(queries))
+	}
+	if !queries[0].Matches(&Page{Tags: []string{"reading"}}) {
+		t.Error("extracted query doesn't match its tag")
+	}
+}
+
+func TestRenderQuery(t *testing.T) {
+	bySlug := renderNotes(t, map[string]string{
+		"alpha.md": "---\ntitle: Alpha\ntags: [reading]\ndate: 2024-05-01\n---\nAlpha.\n",
+		"beta.md":  "---\ntitle: Beta & Co\ntags: [reading]\ngrowth: evergreen\n---\nBeta.\n",
+		"list.md":  "---\ntitle: List\n---\n```leafpress-query\ntag: reading SORT title\n```\n",
+		"table.md": "---\ntitle: Table\n---\n```leafpress-query\ntag: reading SORT title AS TABLE\n```\n",
+		"empty.md": "---\ntitle: Empty\n---\n```leafpress-query\ntag: nothing\n```\n",
+		"bad.md":   "---\ntitle: Bad\n---\n```leafpress-query\ntag: <b>\n```\n",
+	}, RenderOptions{BasePath: "/site"})
+
+	tests := []struct {
+		slug string
+		want []string
+	}{
+		{"list", []string{
+			`<ul class="lp-query lp-index">`,
+			`<a class="lp-index-link" href="/site/alpha/"><span class="lp-index-title">Alpha</span></a><time class="lp-index-date" datetime="2024-05-01">`,
+			`<a class="lp-index-link" href="/site/beta/"><span class="lp-index-growth lp-index-growth--evergreen">`,
+			`<span class="lp-index-title">Beta &amp; Co</span>`,
+		}},
+		{"table", []string{
+			`<table class="lp-query">`,
+			`<tr><td><a class="lp-query-link" href="/site/alpha/">Alpha</a></td><td></td><td>reading</td><td><time datetime="2024-05-01">`,
+			`<tr><td><a class="lp-query-link" href="/site/beta/">Beta &amp; Co</a></td>`,
+		}},
+		{"empty", []string{`<p class="lp-query lp-query-empty">No matching pages.</p>`}},
+		{"bad", []string{`<div class="lp-query lp-query-error">Invalid query: unexpected &#34;b&#34;</div>`}},
+	}
+	for _, tt := range tests {
+		page := bySlug[tt.slug]
+		if page == nil {
+			t.Fatalf("no page %q", tt.slug)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(page.HTMLContent, want) {
+				t.Errorf("%s.md doesn't contain %s\n%s", tt.slug, want, page.HTMLContent)
+			}
+		}
+	}
+
+	// Query links are checked by `leafpress check`, which skips wiki-links
+	if strings.Contains(bySlug["table"].HTMLContent, "lp-wikilink") {
+		t.Errorf("table.md renders query links as wiki-links\n%s", bySlug["table"].HTMLContent)
+	}
+	if len(bySlug["bad"].Warnings) == 0 {
+		t.Error("invalid query produced no warning")
+	}
+}
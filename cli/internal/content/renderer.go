@@ -2,18 +2,21 @@ package content
 
 import (
 	"bytes"
-	"fmt"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/yuin/goldmark"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
 // Renderer converts markdown to HTML
@@ -21,7 +24,25 @@ type Renderer struct {
 	md              goldmark.Markdown
 	resolver        *LinkResolver
 	enableWikilinks bool
-	basePath        string // Base path for links (e.g., "/repo-name" for GitHub Pages)
+	enableMath      bool
+	basePath        string                 // Base path for links (e.g., "/repo-name" for GitHub Pages)
+	calloutTypes    map[string]CalloutType // Built-in callout types plus user-defined ones
+	attachments     *AttachmentIndex       // Files that ![[file.png]] embeds resolve against (nil = static/images)
+	shortcodes      ShortcodeRenderer      // Renders {{< shortcodes >}} (nil = leave them as text)
+}
+
+// RenderOptions configures optional markdown features
+type RenderOptions struct {
+	Wikilinks bool                   // Resolve [[wiki-links]]
+	Math      bool                   // Render $inline$ and $$display$$ TeX math to MathML
+	BasePath  string                 // Base path for links (e.g., "/repo-name" for GitHub Pages)
+	Callouts  map[string]CalloutType // Custom callout types, keyed by lowercase type
+
+	// Attachments resolves ![[file.png]] embeds; without it they point at /static/images/
+	Attachments *AttachmentIndex
+
+	// Shortcodes renders {{< name >}} shortcodes; without it they're left as text
+	Shortcodes ShortcodeRenderer
 }
 
 // Buffer pool for markdown rendering (reduces allocations)
@@ -32,19 +53,53 @@ var bufferPool = sync.Pool{
 }
 
 // NewRenderer creates a new markdown renderer
-func NewRenderer(resolver *LinkResolver, enableWikilinks bool, basePath string) *Renderer {
-	md := goldmark.New(
-		goldmark.WithExtensions(
-			extension.GFM, // GitHub Flavored Markdown
-			extension.Typographer,
-			highlighting.NewHighlighting(
-				highlighting.WithStyle("github"),
-				highlighting.WithFormatOptions(
-					chromahtml.WithClasses(true),
-					chromahtml.WithLineNumbers(false),
-				),
+func NewRenderer(resolver *LinkResolver, opts RenderOptions) *Renderer {
+	r := &Renderer{
+		resolver:        resolver,
+		enableWikilinks: opts.Wikilinks,
+		enableMath:      opts.Math,
+		basePath:        opts.BasePath,
+		calloutTypes:    make(map[string]CalloutType, len(calloutTypes)+len(opts.Callouts)),
+		attachments:     opts.Attachments,
+		shortcodes:      opts.Shortcodes,
+	}
+	for name, info := range calloutTypes {
+		r.calloutTypes[name] = info
+	}
+	for name, info := range opts.Callouts {
+		// Fill in what a custom type leaves out from the built-in type or the defaults
+		name = strings.ToLower(name)
+		base, ok := r.calloutTypes[name]
+		if !ok {
+			base = CalloutType{Title: cases.Title(language.Und).String(name), Icon: "📌"}
+		}
+		if info.Title == "" {
+			info.Title = base.Title
+		}
+		if info.Icon == "" {
+			info.Icon = base.Icon
+		}
+		r.calloutTypes[name] = info
+	}
+
+	extensions := []goldmark.Extender{
+		extension.GFM, // GitHub Flavored Markdown
+		extension.Typographer,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle("github"),
+			highlighting.WithFormatOptions(
+				chromahtml.WithClasses(true),
+				chromahtml.WithLineNumbers(false),
 			),
 		),
+		&leafpressExtension{renderer: r}, // Wiki-links, embeds, callouts, block IDs, link decoration
+	}
+	if opts.Math {
+		extensions = append(extensions, MathExtension)
+	}
+
+	r.md = goldmark.New(
+		goldmark.WithExtensions(extensions...),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 		),
@@ -55,337 +110,135 @@ func NewRenderer(resolver *LinkResolver, enableWikilinks bool, basePath string)
 		),
 	)
 
-	return &Renderer{
-		md:              md,
-		resolver:        resolver,
-		enableWikilinks: enableWikilinks,
-		basePath:        basePath,
-	}
+	return r
 }
 
 // Render converts markdown to HTML, processing wiki-links
 func (r *Renderer) Render(content string) (string, []string) {
-	var warnings []string
-
-	// First, process Obsidian image embeds (![[image.png]])
-	processed := r.processObsidianImages(content)
+	result := r.render(content, nil)
+	return result.html, result.warnings
+}
 
-	// Process callouts before markdown conversion
-	processed = r.processCallouts(processed)
+// RenderPage converts a page's markdown to HTML, tracking the page for embed
+// cycle detection. It also sets the page's WarningLines and RefLines.
+func (r *Renderer) RenderPage(page *Page) (string, []string) {
+	result := r.render(page.RawContent, []*Page{page})
 
-	// Then, replace wiki-links with HTML anchors (if enabled)
-	if r.enableWikilinks {
-		processed = r.processWikiLinks(processed, &warnings)
+	// Lines are 0-based in RawContent, or -1 if unknown
+	fileLine := func(line int) int {
+		if line < 0 {
+			return 0
+		}
+		return page.sourceLines.source(line) + 1
 	}
-
-	// Get buffer from pool (reduces allocations)
-	buf := bufferPool.Get().(*bytes.Buffer)
-	buf.Reset()
-	defer bufferPool.Put(buf)
-
-	// Render markdown to HTML
-	if err := r.md.Convert([]byte(processed), buf); err != nil {
-		warnings = append(warnings, "markdown conversion error: "+err.Error())
-		return content, warnings
+	page.WarningLines = make([]int, len(result.warningLines))
+	for i, line := range result.warningLines {
+		page.WarningLines[i] = fileLine(line)
+	}
+	page.RefLines = make(map[string][]int, len(result.refLines))
+	for url, lines := range result.refLines {
+		for _, line := range lines {
+			page.RefLines[url] = append(page.RefLines[url], fileLine(line))
+		}
+		sort.Ints(page.RefLines[url])
 	}
 
-	// Process external links
-	html := r.processExternalLinks(buf.String())
-
-	// Add lazy loading to images
-	html = processLazyImages(html)
-
-	// Convert blockquote citations (- Author) to <cite> elements
-	html = processBlockquoteCitations(html)
-
-	return html, warnings
+	return result.html, result.warnings
 }
 
-// Pre-compiled regexes (compiled once at startup)
-var (
-	obsidianImageRegex = regexp.MustCompile(`!\[\[([^\]|]+?)(?:\|([^\]]+))?\]\]`)
-	codeBlockRegex     = regexp.MustCompile("(?s)```[^`]*```")
-	inlineCodeRegex    = regexp.MustCompile("`[^`]+`")
-	externalLinkRegex  = regexp.MustCompile(`<a\s+href="(https?://[^"]+)"([^>]*)>([^<]+)</a>`)
-	// Callout regex: matches > [!type] or > [!type] title followed by content lines
-	calloutStartRegex = regexp.MustCompile(`(?m)^>\s*\[!(\w+)\](?:\s+(.*))?$`)
-	// Image regex for lazy loading (captures attributes, handles self-closing)
-	imgTagFullRegex = regexp.MustCompile(`<img\s+([^>]*?)\s*/?\s*>`)
-	// Blockquote citation regex: matches <p>- Author</p> or <p>— Author</p> at end of blockquote
-	blockquoteCiteRegex = regexp.MustCompile(`(?s)(<blockquote>\s*(?:<p>.*?</p>\s*)*)<p>\s*[-–—]\s*(.+?)\s*</p>\s*(</blockquote>)`)
-	// Blockquote citation from list: matches single-item <ul><li>Author</li></ul> at end of blockquote
-	// This handles "> - Author" which markdown parses as a list
-	blockquoteCiteListRegex = regexp.MustCompile(`(?s)(<blockquote>\s*(?:<p>.*?</p>\s*)*)<ul>\s*<li>(.+?)</li>\s*</ul>\s*(</blockquote>)`)
-)
-
-// calloutTypes maps callout type to display title and icon
-var calloutTypes = map[string]struct {
-	title string
-	icon  string
-}{
-	"note":      {"Note", "📝"},
-	"tip":       {"Tip", "💡"},
-	"hint":      {"Hint", "💡"},
-	"important": {"Important", "❗"},
-	"warning":   {"Warning", "⚠️"},
-	"caution":   {"Caution", "⚠️"},
-	"danger":    {"Danger", "🔴"},
-	"error":     {"Error", "🔴"},
-	"info":      {"Info", "ℹ️"},
-	"todo":      {"Todo", "☑️"},
-	"example":   {"Example", "📋"},
-	"quote":     {"Quote", "💬"},
-	"question":  {"Question", "❓"},
-	"faq":       {"FAQ", "❓"},
-	"success":   {"Success", "✅"},
-	"check":     {"Check", "✅"},
-	"done":      {"Done", "✅"},
-	"fail":      {"Fail", "❌"},
-	"failure":   {"Failure", "❌"},
-	"bug":       {"Bug", "🐛"},
-	"abstract":  {"Abstract", "📄"},
-	"summary":   {"Summary", "📄"},
-	"tldr":      {"TL;DR", "📄"},
+// renderResult is rendered HTML with its warnings, and the lines of the
+// markdown they come from (0-based, -1 if unknown)
+type renderResult struct {
+	html         string
+	warnings     []string
+	warningLines []int            // Line of each warning
+	refLines     map[string][]int // Lines of each href and src URL in html
 }
 
-// processCallouts converts Obsidian-style callouts to HTML
-// Input: > [!note] Optional title
-//
-//	> Content here
-//
-// Output: <div class="lp-callout lp-callout-note">...</div>
-func (r *Renderer) processCallouts(content string) string {
-	// Extract code blocks to protect them
-	codeBlocks := extractCodeBlocks(content)
-	protectedContent := content
-
-	// Replace code blocks with placeholders
-	for i, block := range codeBlocks {
-		placeholder := fmt.Sprintf("___CODE_BLOCK_%d___", i)
-		protectedContent = strings.Replace(protectedContent, block, placeholder, 1)
-	}
-
-	lines := strings.Split(protectedContent, "\n")
-	var result []string
-	i := 0
-
-	for i < len(lines) {
-		line := lines[i]
-
-		// Check if this line starts a callout
-		matches := calloutStartRegex.FindStringSubmatch(line)
-		if matches != nil {
-			calloutType := strings.ToLower(matches[1])
-			customTitle := ""
-			if len(matches) > 2 {
-				customTitle = strings.TrimSpace(matches[2])
-			}
-
-			// Get callout info or use defaults
-			info, ok := calloutTypes[calloutType]
-			if !ok {
-				info = struct {
-					title string
-					icon  string
-				}{strings.Title(calloutType), "📌"}
-			}
-
-			// Use custom title if provided
-			title := info.title
-			if customTitle != "" {
-				title = customTitle
-			}
-
-			// Collect all content lines (lines starting with >)
-			var contentLines []string
-			i++
-			for i < len(lines) {
-				if strings.HasPrefix(lines[i], ">") {
-					// Check if this line starts a new callout
-					if calloutStartRegex.MatchString(lines[i]) {
-						break
-					}
-					// Remove the leading > and optional space
-					contentLine := strings.TrimPrefix(lines[i], ">")
-					contentLine = strings.TrimPrefix(contentLine, " ")
-					contentLines = append(contentLines, contentLine)
-					i++
-				} else if strings.TrimSpace(lines[i]) == "" {
-					// Empty line might continue the callout if next line has > but is not a new callout
-					if i+1 < len(lines) && strings.HasPrefix(lines[i+1], ">") && !calloutStartRegex.MatchString(lines[i+1]) {
-						contentLines = append(contentLines, "")
-						i++
-					} else {
-						break
-					}
-				} else {
-					break
-				}
-			}
-
-			// Build the callout HTML
-			calloutContent := strings.Join(contentLines, "\n")
-			calloutHTML := fmt.Sprintf(
-				"<div class=\"lp-callout lp-callout-%s\">\n<div class=\"lp-callout-title\"><span class=\"lp-callout-icon\">%s</span> %s</div>\n<div class=\"lp-callout-content\">\n\n%s\n\n</div>\n</div>",
-				calloutType,
-				info.icon,
-				title,
-				calloutContent,
-			)
-			result = append(result, calloutHTML)
-		} else {
-			result = append(result, line)
-			i++
-		}
-	}
-
-	processed := strings.Join(result, "\n")
+// renderState carries per-document data through goldmark's parser context
+type renderState struct {
+	stack    []*Page // Pages being rendered (outermost first) so nested embeds can detect cycles
+	warnings []string
 
-	// Restore code blocks
-	for i, block := range codeBlocks {
-		placeholder := fmt.Sprintf("___CODE_BLOCK_%d___", i)
-		processed = strings.Replace(processed, placeholder, block, 1)
-	}
-
-	return processed
+	// Byte offsets in the parsed markdown, for reporting source lines
+	warningOffsets map[int]int      // Warning index -> offset
+	refOffsets     map[string][]int // URL -> offsets of the links and images using it
+	nodeOffsets    map[ast.Node]int // Offsets of nodes made from several source nodes (embeds, queries)
 }
 
-// processObsidianImages converts Obsidian image embeds to standard markdown
-func (r *Renderer) processObsidianImages(content string) string {
-	// Extract code blocks to protect them
-	codeBlocks := extractCodeBlocks(content)
-	protectedContent := content
-
-	// Replace code blocks with placeholders
-	for i, block := range codeBlocks {
-		placeholder := fmt.Sprintf("___CODE_BLOCK_%d___", i)
-		protectedContent = strings.Replace(protectedContent, block, placeholder, 1)
+// current returns the page being rendered, or nil if unknown
+func (s *renderState) current() *Page {
+	if len(s.stack) == 0 {
+		return nil
 	}
+	return s.stack[len(s.stack)-1]
+}
 
-	// Replace ![[image.png]] with ![image.png](/static/images/image.png)
-	// Replace ![[image.png|alt]] with ![alt](/static/images/image.png)
-	result := obsidianImageRegex.ReplaceAllStringFunc(protectedContent, func(match string) string {
-		submatches := obsidianImageRegex.FindStringSubmatch(match)
-		if len(submatches) < 2 {
-			return match
-		}
-
-		filename := strings.TrimSpace(submatches[1])
-		alt := filename
-		if len(submatches) > 2 && submatches[2] != "" {
-			alt = strings.TrimSpace(submatches[2])
-		}
-
-		// URL-encode spaces in filename
-		encodedFilename := strings.ReplaceAll(filename, " ", "%20")
-
-		return fmt.Sprintf("![%s](/static/images/%s)", alt, encodedFilename)
-	})
+var renderStateKey = parser.NewContextKey()
 
-	// Restore code blocks
-	for i, block := range codeBlocks {
-		placeholder := fmt.Sprintf("___CODE_BLOCK_%d___", i)
-		result = strings.Replace(result, placeholder, block, 1)
+// getRenderState returns the render state stored in the parser context
+func getRenderState(pc parser.Context) *renderState {
+	if state, ok := pc.Get(renderStateKey).(*renderState); ok {
+		return state
 	}
-
-	return result
+	state := &renderState{}
+	pc.Set(renderStateKey, state)
+	return state
 }
 
-// processWikiLinks replaces [[links]] with HTML anchors
-func (r *Renderer) processWikiLinks(content string, warnings *[]string) string {
-	// Extract code blocks and inline code to protect them
-	codeBlocks := extractCodeBlocks(content)
-	protectedContent := content
+// render converts markdown to HTML. stack holds the pages being rendered
+// (outermost first) so nested embeds can detect cycles.
+func (r *Renderer) render(content string, stack []*Page) renderResult {
+	// Get buffer from pool (reduces allocations)
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
 
-	// Replace code blocks with placeholders
-	for i, block := range codeBlocks {
-		placeholder := fmt.Sprintf("___CODE_BLOCK_%d___", i)
-		protectedContent = strings.Replace(protectedContent, block, placeholder, 1)
+	// Use the shared heading ID scheme so anchors match the TOC and [[note#Heading]] links
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	state := &renderState{stack: stack}
+	ctx.Set(renderStateKey, state)
+
+	// Render markdown to HTML, leaving out %% comments %% and rendering shortcodes separately
+	stripped, commentLines := stripComments(content)
+	expanded, shortcodes, shortcodeLines := r.expandShortcodes(stripped, state)
+	html := expanded
+	if err := r.md.Convert([]byte(expanded), buf, parser.WithContext(ctx)); err != nil {
+		state.warnings = append(state.warnings, "markdown conversion error: "+err.Error())
+	} else {
+		html = replaceShortcodePlaceholders(buf.String(), shortcodes)
 	}
 
-	links := ExtractWikiLinks(protectedContent)
-
-	result := protectedContent
-	for _, link := range links {
-		var replacement string
-
-		if r.resolver != nil {
-			resolved := r.resolver.Resolve(link.Target)
-
-			if resolved.Broken {
-				// Broken link - render as span with class
-				replacement = `<span class="lp-broken-link">` + link.Label + `</span>`
-				*warnings = append(*warnings, "broken link: [["+link.Target+"]]")
-			} else {
-				// Valid link
-				if resolved.Ambiguous {
-					*warnings = append(*warnings, "ambiguous link: [["+link.Target+"]]")
-				}
-				replacement = `<a class="lp-wikilink" href="` + r.basePath + resolved.Page.Permalink + `">` + link.Label + `</a>`
-			}
-		} else {
-			// No resolver - just render the label
-			replacement = link.Label
-		}
-
-		result = replaceFirst(result, link.Raw, replacement)
+	// Map offsets in the parsed markdown back to lines of content
+	starts := lineStarts(expanded)
+	line := func(offset int) int {
+		return commentLines.source(shortcodeLines.source(lineAt(starts, offset)))
 	}
-
-	// Restore code blocks
-	for i, block := range codeBlocks {
-		placeholder := fmt.Sprintf("___CODE_BLOCK_%d___", i)
-		result = strings.Replace(result, placeholder, block, 1)
+	result := renderResult{
+		html:         html,
+		warnings:     state.warnings,
+		warningLines: make([]int, len(state.warnings)),
+		refLines:     make(map[string][]int, len(state.refOffsets)),
 	}
-
-	return result
-}
-
-// extractCodeBlocks extracts code blocks and inline code from markdown
-func extractCodeBlocks(content string) []string {
-	var blocks []string
-
-	// Extract fenced code blocks (```...```)
-	blocks = append(blocks, codeBlockRegex.FindAllString(content, -1)...)
-
-	// Extract inline code (`...`)
-	blocks = append(blocks, inlineCodeRegex.FindAllString(content, -1)...)
-
-	return blocks
-}
-
-// replaceFirst replaces only the first occurrence
-func replaceFirst(s, old, new string) string {
-	i := indexOf(s, old)
-	if i < 0 {
-		return s
+	for i := range state.warnings {
+		result.warningLines[i] = -1
+		if offset, ok := state.warningOffsets[i]; ok {
+			result.warningLines[i] = line(offset)
+		}
 	}
-	return s[:i] + new + s[i+len(old):]
-}
-
-func indexOf(s, substr string) int {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
+	for url, offsets := range state.refOffsets {
+		for _, offset := range offsets {
+			result.refLines[url] = append(result.refLines[url], line(offset))
 		}
 	}
-	return -1
+	return result
 }
 
-// processExternalLinks adds target="_blank" and class to external links
-func (r *Renderer) processExternalLinks(html string) string {
-	return externalLinkRegex.ReplaceAllStringFunc(html, func(match string) string {
-		submatches := externalLinkRegex.FindStringSubmatch(match)
-		if len(submatches) < 4 {
-			return match
-		}
-
-		href := submatches[1]
-		attrs := submatches[2]
-		text := submatches[3]
-
-		return `<a class="lp-external" href="` + href + `" target="_blank" rel="noopener"` + attrs + `>` + text + ` ↗</a>`
-	})
-}
+// Pre-compiled regexes (compiled once at startup)
+var (
+	// Image regex for lazy loading raw HTML images (captures attributes, handles self-closing)
+	imgTagFullRegex = regexp.MustCompile(`<img\s+([^>]*?)\s*/?\s*>`)
+)
 
 // processLazyImages adds lazy loading attributes to all images
 func processLazyImages(html string) string {
@@ -403,22 +256,9 @@ func processLazyImages(html string) string {
 	})
 }
 
-// processBlockquoteCitations converts blockquote paragraphs starting with - or — to <cite>
-// Input:  <blockquote><p>Quote text</p><p>- Author Name</p></blockquote>
-// Output: <blockquote><p>Quote text</p><cite>Author Name</cite></blockquote>
-// Also handles: <blockquote><p>Quote text</p><ul><li>Author</li></ul></blockquote>
-// (which is what "> - Author" produces in markdown)
-func processBlockquoteCitations(html string) string {
-	// First, handle explicit dash/em-dash in paragraph
-	result := blockquoteCiteRegex.ReplaceAllString(html, `$1<cite>$2</cite>$3`)
-	// Then, handle single-item list (from "> - Author" syntax)
-	result = blockquoteCiteListRegex.ReplaceAllString(result, `$1<cite>$2</cite>$3`)
-	return result
-}
-
 // RenderPages renders HTML content for all pages in parallel
 // If resolver is nil, a new one will be created
-func RenderPages(pages []*Page, enableWikilinks bool, resolver *LinkResolver, basePath string) []string {
+func RenderPages(pages []*Page, resolver *LinkResolver, opts RenderOptions) []string {
 	if len(pages) == 0 {
 		return nil
 	}
@@ -426,7 +266,7 @@ func RenderPages(pages []*Page, enableWikilinks bool, resolver *LinkResolver, ba
 	if resolver == nil {
 		resolver = NewLinkResolver(pages)
 	}
-	renderer := NewRenderer(resolver, enableWikilinks, basePath)
+	renderer := NewRenderer(resolver, opts)
 
 	numWorkers := runtime.NumCPU()
 	if numWorkers > len(pages) {
@@ -444,8 +284,9 @@ func RenderPages(pages []*Page, enableWikilinks bool, resolver *LinkResolver, ba
 		go func() {
 			defer wg.Done()
 			for page := range pageChan {
-				html, warnings := renderer.Render(page.RawContent)
+				html, warnings := renderer.RenderPage(page)
 				page.HTMLContent = html
+				page.Warnings = warnings
 
 				// Calculate reading time
 				page.WordCount = CountWords(html)
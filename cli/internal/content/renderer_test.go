@@ -0,0 +1,103 @@
+package content
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/render-golden")
+
+// renderFixtures scans a directory of notes and renders them with wiki-links and math
+func renderFixtures(tb testing.TB, dir string) []*Page {
+	tb.Helper()
+	pages, err := NewScanner(dir, nil).Scan()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	RenderPages(pages, NewLinkResolver(pages), RenderOptions{Wikilinks: true, Math: true})
+	return pages
+}
+
+// TestRenderGolden compares rendered notes with the HTML the renderer produced
+// before its regex passes became goldmark extensions. Only run with -update
+// when an output change is intended.
+func TestRenderGolden(t *testing.T) {
+	for _, page := range renderFixtures(t, filepath.Join("testdata", "render")) {
+		name := strings.ReplaceAll(strings.TrimSuffix(filepath.ToSlash(page.SourcePath), ".md"), "/", "-") + ".html"
+		golden := filepath.Join("testdata", "render-golden", name)
+		t.Run(name, func(t *testing.T) {
+			if *update {
+				if err := os.WriteFile(golden, []byte(page.HTMLContent), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.HTMLContent != string(want) {
+				t.Errorf("%s doesn't match %s\ngot:\n%s\nwant:\n%s", page.SourcePath, golden, page.HTMLContent, want)
+			}
+		})
+	}
+}
+
+// writeBenchVault writes count notes using the syntax the renderer extends:
+// wiki-links, callouts, block embeds, external links, images and citations
+func writeBenchVault(b *testing.B, count int) string {
+	b.Helper()
+	dir := b.TempDir()
+	for i := 1; i <= count; i++ {
+		target := i%count + 1
+		note := fmt.Sprintf(`---
+title: Page %[1]d
+tags: [topic-%[3]d]
+---
+
+# Page %[1]d
+
+Some text linking to [[page-%[2]d]] and [[page-%[2]d|another label]], with
+**bold**, _emphasis_ and `+"`code`"+`.
+
+> [!note] About [[page-%[2]d]]
+> See the [reference](https://example.com/page-%[1]d) and `+"`[[not-a-link]]`"+`.
+
+> Notes are the atoms of thought.
+> - Someone
+
+![[page-%[2]d#^ref-%[2]d]]
+
+## Details
+
+- First point with [[page-%[2]d#Details]]
+- Second point
+
+Inline ![diagram](/static/images/diagram-%[1]d.png) and a block reference ^ref-%[1]d
+`, i, target, i%20)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("page-%d.md", i)), []byte(note), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// BenchmarkRender measures rendering a vault of 1000 notes. Compare revisions
+// with benchmark/render.sh, which runs it against both.
+func BenchmarkRender(b *testing.B) {
+	pages, err := NewScanner(writeBenchVault(b, 1000), nil).Scan()
+	if err != nil {
+		b.Fatal(err)
+	}
+	resolver := NewLinkResolver(pages)
+	opts := RenderOptions{Wikilinks: true, Math: true}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RenderPages(pages, resolver, opts)
+	}
+}
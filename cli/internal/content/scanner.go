@@ -1,7 +1,9 @@
 package content
 
 import (
+	"errors"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -13,6 +15,9 @@ var ReservedPaths = map[string]bool{
 	"leafpress.json": true,
 	"style.css":      true,
 	"static":         true,
+	"layouts":        true,
+	"themes":         true,
+	"fonts":          true,
 	"_site":          true,
 	".leafpress":     true,
 	".git":           true,
@@ -22,10 +27,22 @@ var ReservedPaths = map[string]bool{
 	"docs":           true, // Ignore docs folder
 }
 
-// Scanner scans the content directory for markdown files
+// templateDirs are reserved directories that hold templates rather than
+// notes. Markdown files put there by mistake are reported by Skipped, except
+// in theme folders, which may come with a README.
+var templateDirs = map[string]bool{
+	"layouts": true,
+	"themes":  true,
+}
+
+// Scanner scans the content directory for markdown files and attachments
 type Scanner struct {
-	rootDir     string
-	ignorePaths map[string]bool
+	rootDir        string
+	ignorePaths    map[string]bool
+	normalizeSlugs bool                 // Make slugs from file paths and slug overrides URL-safe (see NormalizeSlug)
+	gitDates       map[string]FileDates // Commit times by slash-separated path (nil = use file times)
+	attachments    []*Attachment        // Non-markdown files found by the last Scan
+	skipped        []string             // Markdown files in template directories found by the last Scan
 }
 
 // NewScanner creates a new content scanner
@@ -37,6 +54,16 @@ func NewScanner(rootDir string, ignore []string) *Scanner {
 	return &Scanner{rootDir: rootDir, ignorePaths: ignorePaths}
 }
 
+// SetNormalizeSlugs enables or disables URL-safe slugs for file paths
+func (s *Scanner) SetNormalizeSlugs(normalize bool) {
+	s.normalizeSlugs = normalize
+}
+
+// SetGitDates sets the commit times used for pages without frontmatter dates
+func (s *Scanner) SetGitDates(dates map[string]FileDates) {
+	s.gitDates = dates
+}
+
 // fileEntry holds info needed to parse a file
 type fileEntry struct {
 	absPath string
@@ -44,10 +71,14 @@ type fileEntry struct {
 	info    os.FileInfo
 }
 
-// Scan walks the directory tree and returns all markdown files
+// Scan walks the directory tree and returns all markdown files.
+// Other files are collected as attachments, see Attachments.
 func (s *Scanner) Scan() ([]*Page, error) {
 	// Phase 1: Collect file paths (fast, sequential walk)
 	var files []fileEntry
+	var static []*Attachment
+	s.attachments = nil
+	s.skipped = nil
 
 	err := filepath.WalkDir(s.rootDir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
@@ -68,6 +99,22 @@ func (s *Scanner) Scan() ([]*Page, error) {
 		// Check if this is a reserved path
 		topLevel := strings.Split(relPath, string(filepath.Separator))[0]
 		if ReservedPaths[topLevel] {
+			// static/ is copied as a whole, but its files can still be embedded by name
+			if topLevel == "static" && !strings.HasPrefix(d.Name(), ".") {
+				if !d.IsDir() {
+					static = append(static, &Attachment{SourcePath: relPath, Static: true})
+				}
+				return nil
+			}
+			if templateDirs[topLevel] && !strings.HasPrefix(d.Name(), ".") {
+				if topLevel == "themes" && d.IsDir() && filepath.Dir(relPath) == "themes" && isThemeDir(path) {
+					return filepath.SkipDir
+				}
+				if !d.IsDir() && filepath.Ext(path) == ".md" {
+					s.skipped = append(s.skipped, relPath)
+				}
+				return nil
+			}
 			if d.IsDir() {
 				return filepath.SkipDir
 			}
@@ -90,8 +137,13 @@ func (s *Scanner) Scan() ([]*Page, error) {
 			return nil
 		}
 
-		// Only process markdown files
-		if d.IsDir() || filepath.Ext(path) != ".md" {
+		if d.IsDir() {
+			return nil
+		}
+
+		// Anything else is an attachment
+		if filepath.Ext(path) != ".md" {
+			s.attachments = append(s.attachments, &Attachment{SourcePath: relPath})
 			return nil
 		}
 
@@ -109,6 +161,9 @@ func (s *Scanner) Scan() ([]*Page, error) {
 		return nil, err
 	}
 
+	// Vault attachments come first so they win filename ties with static/
+	s.attachments = append(s.attachments, static...)
+
 	// Phase 2: Parse files in parallel
 	if len(files) == 0 {
 		return nil, nil
@@ -157,6 +212,51 @@ func (s *Scanner) Scan() ([]*Page, error) {
 	return pages, nil
 }
 
+// Attachments returns the non-markdown files found by the last Scan,
+// including files under static/
+func (s *Scanner) Attachments() []*Attachment {
+	return s.attachments
+}
+
+// Skipped returns the markdown files the last Scan left out because they're
+// in a directory reserved for templates: layouts/, or themes/ outside a theme
+func (s *Scanner) Skipped() []string {
+	return s.skipped
+}
+
+// IsAttachmentPath reports whether a relative path is a file Scan would
+// index as an attachment outside static/
+func IsAttachmentPath(relPath string, ignore []string) bool {
+	if filepath.Ext(relPath) == ".md" {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	if ReservedPaths[parts[0]] {
+		return false
+	}
+	for _, ignored := range ignore {
+		if parts[0] == ignored {
+			return false
+		}
+	}
+	for _, part := range parts {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// isThemeDir reports whether a directory has any of the files a theme is made of
+func isThemeDir(dir string) bool {
+	for _, name := range []string{"theme.json", "style.css", "layouts", "static"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 // parsePage reads and parses a markdown file into a Page
 func (s *Scanner) parsePage(absPath, relPath string, info os.FileInfo) (*Page, error) {
 	// Read file content
@@ -168,31 +268,72 @@ func (s *Scanner) parsePage(absPath, relPath string, info os.FileInfo) (*Page, e
 	// Parse frontmatter
 	fm, body, err := ParseFrontmatter(string(content))
 	if err != nil {
+		var fmErr *FrontmatterError
+		if errors.As(err, &fmErr) {
+			fmErr.Path = relPath
+		}
 		return nil, err
 	}
 
-	// Parse created date (priority: date > created > createdAt > file mod time)
+	// Map body lines to file lines, for reporting where problems are. The
+	// body is what follows the frontmatter once line endings are normalized.
+	normalized := strings.ReplaceAll(strings.TrimPrefix(string(content), "\ufeff"), "\r\n", "\n")
+	bodyStart := 0
+	if strings.HasSuffix(normalized, body) {
+		bodyStart = strings.Count(normalized[:len(normalized)-len(body)], "\n")
+	}
+
+	// Drop %% comments %% so they never reach the site, search index or link graph
+	body, commentLines := stripComments(body)
+
+	sourceLines := make(lineMap, strings.Count(body, "\n")+1)
+	for i := range sourceLines {
+		sourceLines[i] = commentLines.source(i) + bodyStart
+	}
+
+	// Parse created date (priority: date > created > createdAt > first commit > file mod time)
+	gitDates, inGit := s.gitDates[filepath.ToSlash(relPath)]
 	createdStr := fm.GetCreatedDate()
 	created, err := ParseDate(createdStr)
 	if err != nil || created.IsZero() {
 		created = info.ModTime()
+		if inGit {
+			created = gitDates.Created
+		}
 	}
 
-	// Parse modified date (priority: modified > updated > updatedAt)
+	// Parse modified date (priority: modified > updated > updatedAt > last commit)
 	modifiedStr := fm.GetModifiedDate()
 	modified, _ := ParseDate(modifiedStr)
+	if modified.IsZero() && inGit {
+		modified = gitDates.Modified
+	}
 	// Note: modified can be zero if not specified
 
 	// Date is used for display/sorting, same as created
 	date := created
 
-	// Generate slug
-	slug := generateSlug(relPath)
+	// Generate slug (priority: permalink > slug + folder > file path)
+	pathSlug := generateSlug(relPath)
+	slug := pathSlug
+	if s.normalizeSlugs {
+		slug = NormalizeSlug(slug)
+	}
+	switch {
+	case fm.Permalink != "":
+		slug = strings.Trim(fm.Permalink, "/")
+	case fm.Slug != "":
+		override := fm.Slug
+		if s.normalizeSlugs {
+			override = NormalizeSlug(override)
+		}
+		slug = path.Join(path.Dir(slug), override)
+	}
 
 	// Generate title from filename if not set
 	title := fm.Title
 	if title == "" {
-		title = generateTitleFromSlug(filepath.Base(slug))
+		title = generateTitleFromSlug(filepath.Base(pathSlug))
 	}
 
 	// Check if this is a section index
@@ -208,12 +349,16 @@ func (s *Scanner) parsePage(absPath, relPath string, info os.FileInfo) (*Page, e
 		Date:                date,
 		Created:             created,
 		Modified:            modified,
-		Tags:                fm.Tags,
+		Tags:                mergeTags(fm.Tags, ExtractInlineTags(body)),
 		Draft:               fm.Draft,
+		Publish:             fm.Publish,
 		Growth:              fm.Growth,
 		TOC:                 fm.TOC,
 		ShowList:            fm.ShowList,
 		Image:               fm.Image,
+		Aliases:             fm.Aliases,
+		RedirectFrom:        fm.RedirectFrom,
+		Params:              fm.Params,
 		SourcePath:          relPath,
 		Slug:                slug,
 		OutputPath:          outputPath,
@@ -222,21 +367,26 @@ func (s *Scanner) parsePage(absPath, relPath string, info os.FileInfo) (*Page, e
 		IsIndex:             isIndex,
 		SectionSort:         fm.Sort,
 		ReadingTimeOverride: fm.ReadingTime,
+		sourceLines:         sourceLines,
 	}
 
 	return page, nil
 }
 
-// ParseSingleFile parses a single markdown file and returns a Page
-func ParseSingleFile(rootDir, relPath string) (*Page, error) {
-	absPath := filepath.Join(rootDir, relPath)
+// ParseFile parses a single markdown file with the scanner's settings
+func (s *Scanner) ParseFile(relPath string) (*Page, error) {
+	absPath := filepath.Join(s.rootDir, relPath)
 	info, err := os.Stat(absPath)
 	if err != nil {
 		return nil, err
 	}
+	return s.parsePage(absPath, relPath, info)
+}
 
-	scanner := &Scanner{rootDir: rootDir}
-	return scanner.parsePage(absPath, relPath, info)
+// ParseSingleFile parses a single markdown file and returns a Page
+func ParseSingleFile(rootDir, relPath string, normalizeSlugs bool) (*Page, error) {
+	scanner := &Scanner{rootDir: rootDir, normalizeSlugs: normalizeSlugs}
+	return scanner.ParseFile(relPath)
 }
 
 // generateSlug creates a URL slug from a file path
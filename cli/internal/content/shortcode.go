@@ -0,0 +1,210 @@
+package content
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ShortcodeRenderer renders {{< name >}} shortcodes. It's implemented by the
+// templates package, which holds the built-in and site shortcode templates.
+type ShortcodeRenderer interface {
+	HasShortcode(name string) bool
+	// RenderShortcode renders a shortcode. inner is the rendered HTML between
+	// the tags of a paired shortcode, and "" for a single tag.
+	RenderShortcode(name string, params map[string]string, inner string, page *Page) (string, error)
+}
+
+var (
+	// shortcodeTagRegex matches {{< name params >}}, {{< /name >}} and {{< name params />}}
+	shortcodeTagRegex = regexp.MustCompile(`\{\{<\s*(/?)([A-Za-z][\w-]*)((?:\s+(?:[\w-]+=(?:"[^"]*"|'[^']*')|"[^"]*"|'[^']*'|[^\s>"']+))*)\s*(/?)\s*>\}\}`)
+	// shortcodeParamRegex matches key="value", key='value', key=value or a positional value
+	shortcodeParamRegex = regexp.MustCompile(`([\w-]+)=(?:"([^"]*)"|'([^']*)'|([^\s"']+))|"([^"]*)"|'([^']*)'|(\S+)`)
+)
+
+// shortcodeTag is a shortcode tag found in markdown
+type shortcodeTag struct {
+	start, end  int // Byte offsets of the tag in the markdown
+	name        string
+	params      map[string]string // Named params, plus positional ones under "0", "1", ...
+	closing     bool              // {{< /name >}}
+	selfClosing bool              // {{< name />}}
+}
+
+// shortcodePlaceholder stands in for a rendered shortcode while goldmark
+// parses the rest of the markdown. Private-use characters can't clash with text.
+func shortcodePlaceholder(i int) string {
+	return "\ue000" + strconv.Itoa(i) + "\ue001"
+}
+
+// expandShortcodes replaces the shortcodes in markdown with placeholders,
+// returning the HTML for each one and the line of content each line of the
+// result starts on. Shortcodes in code blocks and code spans are left alone.
+// Paired shortcodes render their inner markdown first.
+func (r *Renderer) expandShortcodes(content string, state *renderState) (string, []string, lineMap) {
+	if r.shortcodes == nil || !strings.Contains(content, "{{<") {
+		return content, nil, nil
+	}
+	tags := findShortcodeTags(content)
+	if len(tags) == 0 {
+		return content, nil, nil
+	}
+
+	starts := lineStarts(content)
+	var out lineWriter
+	var rendered []string
+	last := 0
+	for i := 0; i < len(tags); i++ {
+		tag := tags[i]
+		raw := content[tag.start:tag.end]
+		// Where the tag lands in the result: the text before it is copied as-is
+		offset := out.Len() + tag.start - last
+		if tag.closing {
+			state.warnAt(offset, "unexpected closing shortcode: "+raw)
+			continue
+		}
+		closing := matchingShortcodeClose(tags, i)
+		if !r.shortcodes.HasShortcode(tag.name) {
+			state.warnAt(offset, "unknown shortcode: "+raw)
+			if closing >= 0 {
+				i = closing
+			}
+			continue
+		}
+
+		end, inner := tag.end, ""
+		if closing >= 0 {
+			markdown := strings.Trim(content[tag.end:tags[closing].start], "\n")
+			from := len(state.warnings)
+			result := r.render(markdown, state.stack)
+			inner = result.html
+			state.warnings = append(state.warnings, result.warnings...)
+			state.locate(from, offset)
+			end = tags[closing].end
+			i = closing
+		}
+
+		html, err := r.shortcodes.RenderShortcode(tag.name, tag.params, inner, state.current())
+		if err != nil {
+			state.warnAt(offset, "shortcode error: "+raw+": "+err.Error())
+			continue
+		}
+		state.addHTMLRefs(html, offset)
+
+		out.write(content[last:tag.start], lineAt(starts, last))
+		out.write(shortcodePlaceholder(len(rendered)), lineAt(starts, tag.start))
+		rendered = append(rendered, html)
+		last = end
+	}
+	out.write(content[last:], lineAt(starts, last))
+
+	return out.String(), rendered, out.lines
+}
+
+// replaceShortcodePlaceholders puts rendered shortcodes into the HTML,
+// unwrapping the paragraph goldmark puts around one on its own line
+func replaceShortcodePlaceholders(html string, rendered []string) string {
+	for i, sc := range rendered {
+		placeholder := shortcodePlaceholder(i)
+		html = strings.Replace(html, "<p>"+placeholder+"</p>", sc, 1)
+		html = strings.Replace(html, placeholder, sc, 1)
+	}
+	return html
+}
+
+// matchingShortcodeClose returns the index of the tag that closes tags[open],
+// or -1 if it's a single tag
+func matchingShortcodeClose(tags []shortcodeTag, open int) int {
+	if tags[open].selfClosing {
+		return -1
+	}
+	depth := 0
+	for j := open + 1; j < len(tags); j++ {
+		if tags[j].name != tags[open].name || tags[j].selfClosing {
+			continue
+		}
+		if !tags[j].closing {
+			depth++
+			continue
+		}
+		if depth == 0 {
+			return j
+		}
+		depth--
+	}
+	return -1
+}
+
+// findShortcodeTags returns the shortcode tags in markdown, in order,
+// skipping fenced code blocks and code spans
+func findShortcodeTags(content string) []shortcodeTag {
+	var tags []shortcodeTag
+	inFence := false
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineStart := offset
+		offset += len(line)
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.Contains(line, "{{<") {
+			continue
+		}
+
+		for i := 0; i < len(line); {
+			if line[i] == '`' {
+				// Skip code spans
+				run := len(line[i:]) - len(strings.TrimLeft(line[i:], "`"))
+				span := run
+				if end := strings.Index(line[i+run:], line[i:i+run]); end >= 0 {
+					span += end + run
+				}
+				i += span
+				continue
+			}
+			loc := shortcodeTagRegex.FindStringSubmatchIndex(line[i:])
+			if loc == nil {
+				break
+			}
+			// A code span before the tag is skipped on the next pass
+			if tick := strings.IndexByte(line[i:i+loc[0]], '`'); tick >= 0 {
+				i += tick
+				continue
+			}
+
+			match := line[i+loc[0] : i+loc[1]]
+			groups := shortcodeTagRegex.FindStringSubmatch(match)
+			params, selfClosing := strings.TrimSpace(groups[3]), groups[4] == "/"
+			// The params pattern also takes the / of "{{< name />}}"
+			if params == "/" || strings.HasSuffix(params, " /") {
+				params, selfClosing = strings.TrimSuffix(params, "/"), true
+			}
+			tags = append(tags, shortcodeTag{
+				start:       lineStart + i + loc[0],
+				end:         lineStart + i + loc[1],
+				name:        groups[2],
+				params:      parseShortcodeParams(params),
+				closing:     groups[1] == "/",
+				selfClosing: selfClosing,
+			})
+			i += loc[1]
+		}
+	}
+	return tags
+}
+
+// parseShortcodeParams parses key="value" pairs and positional values
+func parseShortcodeParams(s string) map[string]string {
+	params := make(map[string]string)
+	position := 0
+	for _, m := range shortcodeParamRegex.FindAllStringSubmatch(s, -1) {
+		if m[1] != "" {
+			params[m[1]] = m[2] + m[3] + m[4]
+			continue
+		}
+		params[strconv.Itoa(position)] = m[5] + m[6] + m[7]
+		position++
+	}
+	return params
+}
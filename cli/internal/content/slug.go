@@ -0,0 +1,79 @@
+package content
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// transliterations maps letters that don't decompose into a base letter and accents
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'ł': "l", 'þ': "th", 'ı': "i",
+}
+
+// NormalizeSlug makes each segment of a slug URL-safe: lowercase, accents
+// transliterated (é -> e), and runs of spaces and punctuation replaced by a
+// single hyphen. Letters from other scripts are kept.
+func NormalizeSlug(slug string) string {
+	if slug == "" {
+		return ""
+	}
+	segments := strings.Split(slug, "/")
+	for i, segment := range segments {
+		segments[i] = normalizeSlugSegment(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func normalizeSlugSegment(segment string) string {
+	// Split letters from their accents, then drop the accents
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(t, strings.ToLower(segment))
+	if err != nil {
+		stripped = strings.ToLower(segment)
+	}
+
+	var b strings.Builder
+	hyphen := false
+	for _, r := range stripped {
+		if s, ok := transliterations[r]; ok {
+			b.WriteString(s)
+			hyphen = false
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	normalized := strings.TrimSuffix(b.String(), "-")
+	if normalized == "" {
+		// Nothing URL-safe left (e.g., "!!!"), keep the segment as written
+		return segment
+	}
+	return normalized
+}
+
+// CheckSlugs returns an error if two pages share a slug, since they would
+// overwrite each other's output
+func CheckSlugs(pages []*Page) error {
+	bySlug := make(map[string]*Page, len(pages))
+	for _, page := range pages {
+		if other, ok := bySlug[page.Slug]; ok && other.SourcePath != page.SourcePath {
+			return fmt.Errorf("slug collision: %s and %s both map to %s", other.SourcePath, page.SourcePath, page.Permalink)
+		}
+		bySlug[page.Slug] = page
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package content
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeSlug(t *testing.T) {
+	tests := []struct {
+		slug string
+		want string
+	}{
+		{"", ""},
+		{"Notes/My Note (draft)", "notes/my-note-draft"},
+		{"café/Straße", "cafe/strasse"},
+		{"  Leading and trailing  ", "leading-and-trailing"},
+		{"日本語 ノート", "日本語-ノート"},
+		{"!!!", "!!!"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSlug(tt.slug); got != tt.want {
+			t.Errorf("NormalizeSlug(%q) = %q, want %q", tt.slug, got, tt.want)
+		}
+	}
+}
+
+func TestScannerSlugOverrides(t *testing.T) {
+	dir := t.TempDir()
+	notes := map[string]string{
+		"Guides/Getting Started.md": "---\nslug: My Intro\n---\n",
+		"Guides/Other Page.md":      "---\npermalink: /About Us/\n---\n",
+		"Guides/Plain Page.md":      "",
+	}
+	for name, content := range notes {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		normalize bool
+		want      map[string]string
+	}{
+		{false, map[string]string{
+			"Guides/Getting Started.md": "Guides/My Intro",
+			"Guides/Other Page.md":      "About Us",
+			"Guides/Plain Page.md":      "Guides/Plain Page",
+		}},
+		{true, map[string]string{
+			"Guides/Getting Started.md": "guides/my-intro",
+			"Guides/Other Page.md":      "About Us",
+			"Guides/Plain Page.md":      "guides/plain-page",
+		}},
+	}
+	for _, tt := range tests {
+		scanner := NewScanner(dir, nil)
+		scanner.SetNormalizeSlugs(tt.normalize)
+		pages, err := scanner.Scan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range pages {
+			if want := tt.want[filepath.ToSlash(p.SourcePath)]; p.Slug != want {
+				t.Errorf("normalize=%v: %s has slug %q, want %q", tt.normalize, p.SourcePath, p.Slug, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package content
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// htmlRefRegex matches the href and src attributes in rendered or raw HTML
+var htmlRefRegex = regexp.MustCompile(`\s(?:href|src)="([^"]*)"`)
+
+// lineMap maps the lines of transformed markdown to the lines they came from
+// (both 0-based). A nil map means the lines are unchanged.
+type lineMap []int
+
+// source returns the line that line came from
+func (m lineMap) source(line int) int {
+	if len(m) == 0 {
+		return line
+	}
+	if line < len(m) {
+		return m[line]
+	}
+	return m[len(m)-1] + line - (len(m) - 1)
+}
+
+// lineWriter builds transformed text, remembering which line of the input
+// each output line starts on
+type lineWriter struct {
+	sb      strings.Builder
+	lines   lineMap
+	midLine bool // The last byte written wasn't a newline
+}
+
+// write appends s, whose first byte is on input line line
+func (w *lineWriter) write(s string, line int) {
+	for len(s) > 0 {
+		if !w.midLine {
+			w.lines = append(w.lines, line)
+			w.midLine = true
+		}
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			w.sb.WriteString(s)
+			return
+		}
+		w.sb.WriteString(s[:i+1])
+		s = s[i+1:]
+		line++
+		w.midLine = false
+	}
+}
+
+func (w *lineWriter) String() string {
+	return w.sb.String()
+}
+
+// Len returns the number of bytes written
+func (w *lineWriter) Len() int {
+	return w.sb.Len()
+}
+
+// lineStarts returns the byte offset of each line in s
+func lineStarts(s string) []int {
+	starts := []int{0}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lineAt returns the 0-based line of a byte offset, given the lineStarts of the text
+func lineAt(starts []int, offset int) int {
+	return sort.SearchInts(starts, offset+1) - 1
+}
+
+// warnAt records a warning about the markdown at a byte offset of the parsed source
+func (s *renderState) warnAt(offset int, w string) {
+	s.warnings = append(s.warnings, w)
+	s.locate(len(s.warnings)-1, offset)
+}
+
+// locate records offset as the position of the warnings added since index from
+func (s *renderState) locate(from, offset int) {
+	if offset < 0 {
+		return
+	}
+	if s.warningOffsets == nil {
+		s.warningOffsets = make(map[int]int)
+	}
+	for i := from; i < len(s.warnings); i++ {
+		if _, ok := s.warningOffsets[i]; !ok {
+			s.warningOffsets[i] = offset
+		}
+	}
+}
+
+// setNodeOffset records the offset of a node whose source position goldmark doesn't know
+func (s *renderState) setNodeOffset(node ast.Node, offset int) {
+	if s.nodeOffsets == nil {
+		s.nodeOffsets = make(map[ast.Node]int)
+	}
+	s.nodeOffsets[node] = offset
+}
+
+// addRef records that url appears in the HTML rendered from the markdown at offset
+func (s *renderState) addRef(url string, offset int) {
+	if offset < 0 {
+		return
+	}
+	if s.refOffsets == nil {
+		s.refOffsets = make(map[string][]int)
+	}
+	s.refOffsets[url] = append(s.refOffsets[url], offset)
+}
+
+// addHTMLRefs records the href and src URLs in a piece of HTML at offset
+func (s *renderState) addHTMLRefs(htmlContent string, offset int) {
+	for _, match := range htmlRefRegex.FindAllStringSubmatch(htmlContent, -1) {
+		s.addRef(html.UnescapeString(match[1]), offset)
+	}
+}
+
+// sourceMapTransformer records where in the markdown each link and image
+// comes from, so checks can report source lines. It runs after the other
+// transformers, which rewrite some of them.
+type sourceMapTransformer struct{}
+
+func (t *sourceMapTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	state := getRenderState(pc)
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Link:
+			state.addRef(string(util.URLEscape(n.Destination, true)), nodeOffset(n, state))
+		case *ast.Image:
+			state.addRef(string(util.URLEscape(n.Destination, true)), nodeOffset(n, state))
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			for i := 0; i < n.Segments.Len(); i++ {
+				segment := n.Segments.At(i)
+				state.addHTMLRefs(string(segment.Value(source)), segment.Start)
+			}
+		case *ast.HTMLBlock:
+			for i := 0; i < n.Lines().Len(); i++ {
+				segment := n.Lines().At(i)
+				state.addHTMLRefs(string(segment.Value(source)), segment.Start)
+			}
+		case *EmbedNode:
+			state.addHTMLRefs(n.HTML, nodeOffset(n, state))
+		case *QueryNode:
+			state.addHTMLRefs(n.HTML, nodeOffset(n, state))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// nodeOffset returns the byte offset in the source where an inline node
+// starts, from the offset its parser recorded, its text, the text before it
+// or its block, in that order. Returns -1 if unknown.
+func nodeOffset(node ast.Node, state *renderState) int {
+	if offset, ok := state.nodeOffsets[node]; ok {
+		return offset
+	}
+
+	offset := -1
+	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := n.(*ast.Text); ok && entering {
+			offset = t.Segment.Start
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if offset >= 0 {
+		return offset
+	}
+
+	for prev := node.PreviousSibling(); prev != nil; prev = prev.PreviousSibling() {
+		if t, ok := prev.(*ast.Text); ok {
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				return t.Segment.Stop + 1
+			}
+			return t.Segment.Stop
+		}
+	}
+
+	for p := node.Parent(); p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock && p.Lines().Len() > 0 {
+			return p.Lines().At(0).Start
+		}
+	}
+	return -1
+}
@@ -0,0 +1,155 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// inlineTagRegex matches #tag after whitespace or at the start of a line
+	inlineTagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+	// tagPrefixRegex matches a #tag at the start of the input
+	tagPrefixRegex = regexp.MustCompile(`^#([\p{L}\p{N}_/-]+)`)
+	// inlineCodeRegex matches `code spans`, which never contain tags
+	inlineCodeRegex = regexp.MustCompile("`+[^`]*`+")
+)
+
+// ExtractInlineTags extracts #tags from a note body, skipping fenced code,
+// inline code and headings. Tags without a letter (#123) are ignored.
+func ExtractInlineTags(content string) []string {
+	var tags []string
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if isFenceLine(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence || atxHeadingRegex.MatchString(line) {
+			continue
+		}
+		line = inlineCodeRegex.ReplaceAllString(line, "")
+		for _, match := range inlineTagRegex.FindAllStringSubmatch(line, -1) {
+			if tag, ok := trimInlineTag(match[1]); ok {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// NormalizeTag returns the key of a tag, which tag pages and lookups use:
+// lowercase, with the slashes and spaces of nested tags cleaned up (" Area // Sub/" -> "area/sub")
+func NormalizeTag(tag string) string {
+	var parts []string
+	for _, part := range strings.Split(strings.ToLower(tag), "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// trimInlineTag trims trailing separators from an inline #tag and checks it isn't purely numeric
+func trimInlineTag(tag string) (string, bool) {
+	tag = strings.TrimRight(tag, "/-")
+	if strings.HasPrefix(tag, "/") {
+		return "", false
+	}
+	for _, r := range tag {
+		if !unicode.IsDigit(r) {
+			return tag, true
+		}
+	}
+	return "", false
+}
+
+// mergeTags appends tags not already present (by NormalizeTag), keeping the first spelling
+func mergeTags(tags []string, more ...[]string) []string {
+	seen := make(map[string]bool, len(tags))
+	var merged []string
+	for _, list := range append([][]string{tags}, more...) {
+		for _, tag := range list {
+			tag = strings.TrimSpace(tag)
+			key := NormalizeTag(tag)
+			if tag == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// KindTag is the NodeKind for inline #tags
+var KindTag = ast.NewNodeKind("Tag")
+
+// TagNode is an inline #tag in the markdown AST
+type TagNode struct {
+	ast.BaseInline
+	Tag  string
+	Href string
+}
+
+// Kind implements ast.Node.Kind
+func (n *TagNode) Kind() ast.NodeKind { return KindTag }
+
+// Dump implements ast.Node.Dump
+func (n *TagNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Tag": n.Tag}, nil)
+}
+
+// tagParser parses inline #tags into links to their tag pages
+type tagParser struct {
+	renderer *Renderer
+}
+
+func (p *tagParser) Trigger() []byte {
+	return []byte{'#'}
+}
+
+func (p *tagParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	// Tags start a word and never appear in headings
+	if prev := block.PrecendingCharacter(); !unicode.IsSpace(prev) {
+		return nil
+	}
+	if _, ok := parent.(*ast.Heading); ok {
+		return nil
+	}
+
+	line, _ := block.PeekLine()
+	match := tagPrefixRegex.FindSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	tag, ok := trimInlineTag(string(match[1]))
+	if !ok {
+		return nil
+	}
+	// Only consume the tag itself, leaving any trailing separators as text
+	block.Advance(1 + len(tag))
+
+	return &TagNode{
+		Tag:  tag,
+		Href: p.renderer.basePath + "/tags/" + NormalizeTag(tag) + "/",
+	}
+}
+
+// renderTag renders an inline #tag as a link to its tag page
+func renderTag(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*TagNode)
+		w.WriteString(`<a class="lp-tag" href="`)
+		w.Write(util.EscapeHTML([]byte(n.Href)))
+		w.WriteString(`">#`)
+		w.Write(util.EscapeHTML([]byte(n.Tag)))
+		w.WriteString(`</a>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,52 @@
+package content
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"Project", "project"},
+		{"area/Sub", "area/sub"},
+		{" Area//Sub/", "area/sub"},
+		{"area / sub", "area/sub"},
+		{"/area/", "area"},
+		{"", ""},
+		{" / ", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTag(tt.tag); got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	got := mergeTags([]string{"Area/Sub", "garden"}, []string{"area / sub", "GARDEN", "new"})
+	want := []string{"Area/Sub", "garden", "new"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeTags() = %q, want %q", got, want)
+	}
+}
+
+func TestQueryTagMatchesNormalized(t *testing.T) {
+	page := &Page{Tags: []string{"Area / Sub"}}
+	for _, source := range []string{"tag: area", "tag: #Area/Sub", "tag: area//sub/", `tag: "area / sub"`} {
+		q, err := ParseQuery(source)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", source, err)
+		}
+		if !q.Matches(page) {
+			t.Errorf("%q doesn't match tag %q", source, page.Tags[0])
+		}
+	}
+
+	q, _ := ParseQuery("tag: area/subway")
+	if q.Matches(page) {
+		t.Errorf("tag: area/subway matches tag %q", page.Tags[0])
+	}
+}
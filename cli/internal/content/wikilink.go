@@ -1,15 +1,27 @@
 package content
 
 import (
+	"bytes"
 	"regexp"
 	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 // WikiLink represents a parsed wiki-link
 type WikiLink struct {
-	Target string // The link target (slug or path)
-	Label  string // Display label (defaults to target)
-	Raw    string // Original raw text including brackets
+	Target   string // The link target (slug or path), without any #fragment
+	Fragment string // Heading text or ^block-id after # (empty if none)
+	Label    string // Display label (defaults to target)
+	Raw      string // Original raw text including brackets
+}
+
+// IsBlockRef returns true if the link targets a block ID ([[note#^id]])
+func (l WikiLink) IsBlockRef() bool {
+	return strings.HasPrefix(l.Fragment, "^")
 }
 
 // wikiLinkRegex matches [[target]] or [[target|label]]
@@ -21,35 +33,222 @@ func ExtractWikiLinks(content string) []WikiLink {
 	var links []WikiLink
 
 	for _, match := range matches {
-		target := strings.TrimSpace(match[1])
-		label := target
-		if len(match) > 2 && match[2] != "" {
-			label = strings.TrimSpace(match[2])
+		links = append(links, newWikiLink(match[1], match[2], match[0]))
+	}
+
+	return links
+}
+
+// newWikiLink builds a WikiLink from the text and optional label inside [[text|label]]
+func newWikiLink(text, label, raw string) WikiLink {
+	text = strings.TrimSpace(text)
+	label = strings.TrimSpace(label)
+	if label == "" {
+		label = text
+	}
+	target, fragment := splitFragment(text)
+
+	return WikiLink{
+		Target:   target,
+		Fragment: fragment,
+		Label:    label,
+		Raw:      raw,
+	}
+}
+
+// KindWikiLink is the NodeKind for wiki-links
+var KindWikiLink = ast.NewNodeKind("WikiLink")
+
+// WikiLinkNode is a [[wiki-link]] in the markdown AST, resolved at parse time
+type WikiLinkNode struct {
+	ast.BaseInline
+	Link   WikiLink
+	Href   string // Resolved URL (empty if broken or there is no resolver)
+	Broken bool
+}
+
+// Kind implements ast.Node.Kind
+func (n *WikiLinkNode) Kind() ast.NodeKind { return KindWikiLink }
+
+// Dump implements ast.Node.Dump
+func (n *WikiLinkNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Target": n.Link.Target, "Href": n.Href}, nil)
+}
+
+// wikiLinkParser parses [[links]], ![[note]] embeds and ![[image.png]] embeds
+type wikiLinkParser struct {
+	renderer *Renderer
+}
+
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'[', '!'}
+}
+
+func (p *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+
+	embed := len(line) > 0 && line[0] == '!'
+	open := 0
+	if embed {
+		open = 1
+	}
+	if !bytes.HasPrefix(line[open:], []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line[open+2:], []byte("]]"))
+	if end <= 0 {
+		return nil
+	}
+	inner := line[open+2 : open+2+end]
+	if bytes.IndexByte(inner, ']') >= 0 {
+		return nil
+	}
+	raw := string(line[:open+2+end+2])
+
+	// Split [[target|label]]; inside tables the pipe is escaped as \|
+	linkText, label := string(inner), ""
+	if idx := bytes.IndexByte(inner, '|'); idx >= 0 {
+		linkText, label = string(inner[:idx]), string(inner[idx+1:])
+		linkText = strings.TrimSuffix(linkText, "\\")
+	}
+	if strings.TrimSpace(linkText) == "" {
+		return nil
+	}
+
+	r := p.renderer
+	state := getRenderState(pc)
+	link := newWikiLink(linkText, label, raw)
+
+	// Warnings, including those from embedded notes, point at the link
+	_, segment := block.PeekLine()
+	from := len(state.warnings)
+	defer state.locate(from, segment.Start)
+
+	if embed {
+		block.Advance(len(raw))
+		var node ast.Node
+		if isNoteEmbed(strings.TrimSpace(linkText)) {
+			e := Embed{Target: link.Target, Fragment: link.Fragment, Raw: raw}
+			node = &EmbedNode{Embed: e, HTML: r.renderEmbed(e, state.stack, &state.warnings)}
+		} else {
+			node = r.fileEmbed(strings.TrimSpace(linkText), strings.TrimSpace(label), raw, &state.warnings)
 		}
+		state.setNodeOffset(node, segment.Start)
+		return node
+	}
 
-		links = append(links, WikiLink{
-			Target: target,
-			Label:  label,
-			Raw:    match[0],
-		})
+	if !r.enableWikilinks {
+		return nil
 	}
+	block.Advance(len(raw))
 
-	return links
+	node := &WikiLinkNode{Link: link}
+	if r.resolver != nil {
+		node.Href, node.Broken = r.resolveWikiLink(link, state.current(), &state.warnings)
+	}
+	node.AppendChild(node, ast.NewString([]byte(link.Label)))
+	return node
+}
+
+// resolveWikiLink returns the URL for a wiki-link, validating any #heading or #^block fragment.
+// broken is true if the link should render as a broken link.
+func (r *Renderer) resolveWikiLink(link WikiLink, current *Page, warnings *[]string) (href string, broken bool) {
+	// Same-page link ([[#Heading]])
+	if link.Target == "" {
+		if current != nil && !r.resolver.HasAnchor(current, link.Fragment) {
+			*warnings = append(*warnings, missingFragmentWarning(link.IsBlockRef())+": "+link.Raw+" in "+current.SourcePath)
+			return "", true
+		}
+		return "#" + FragmentAnchor(link.Fragment), false
+	}
+
+	resolved := r.resolver.Resolve(link.Target)
+	if resolved.Broken {
+		// Links to private notes keep their label as plain text, without a link
+		if r.resolver.IsUnpublished(link.Target) {
+			*warnings = append(*warnings, unpublishedWarning("link", link.Raw, current))
+			return "", false
+		}
+		*warnings = append(*warnings, "broken link: [["+link.Target+"]]")
+		return "", true
+	}
+
+	// Valid link
+	if resolved.Ambiguous {
+		*warnings = append(*warnings, "ambiguous link: [["+link.Target+"]]")
+	}
+
+	href = r.basePath + resolved.Page.Permalink
+	if link.Fragment != "" {
+		if !r.resolver.HasAnchor(resolved.Page, link.Fragment) {
+			*warnings = append(*warnings, missingFragmentWarning(link.IsBlockRef())+": "+link.Raw)
+		}
+		href += "#" + FragmentAnchor(link.Fragment)
+	}
+
+	return href, false
+}
+
+// unpublishedWarning reports a link or embed to a note left out in optIn publish mode
+func unpublishedWarning(kind, raw string, current *Page) string {
+	w := "unpublished " + kind + ": " + raw
+	if current != nil {
+		w += " in " + current.SourcePath
+	}
+	return w
+}
+
+// missingFragmentWarning returns the warning kind for an unresolved #heading or #^block
+func missingFragmentWarning(isBlock bool) string {
+	if isBlock {
+		return "missing block"
+	}
+	return "missing heading"
+}
+
+// renderWikiLink renders a resolved wiki-link as an anchor, or a span if it's broken
+func renderWikiLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*WikiLinkNode)
+	switch {
+	case n.Broken:
+		if entering {
+			w.WriteString(`<span class="lp-broken-link">`)
+		} else {
+			w.WriteString(`</span>`)
+		}
+	case n.Href != "":
+		if entering {
+			w.WriteString(`<a class="lp-wikilink" href="`)
+			w.Write(util.EscapeHTML([]byte(n.Href)))
+			w.WriteString(`">`)
+		} else {
+			w.WriteString(`</a>`)
+		}
+	}
+	// No resolver or an unpublished target - just render the label
+	return ast.WalkContinue, nil
 }
 
 // LinkResolver resolves wiki-links to actual pages
 type LinkResolver struct {
-	pages   []*Page
-	slugMap map[string]*Page   // Exact slug -> page
-	nameMap map[string][]*Page // Filename -> pages (may have duplicates)
+	pages    []*Page
+	slugMap  map[string]*Page   // Exact slug (or file path, if the slug differs) -> page
+	nameMap  map[string][]*Page // Filename -> pages (may have duplicates)
+	aliasMap map[string][]*Page // Alias (from frontmatter) -> pages (may have duplicates)
+	titleMap map[string][]*Page // Title -> pages (may have duplicates)
+	anchors  anchorCache        // Page -> heading and block anchors (built lazily)
+
+	unpublished *LinkResolver // Notes left out in optIn publish mode (nil if none)
 }
 
 // NewLinkResolver creates a new link resolver
 func NewLinkResolver(pages []*Page) *LinkResolver {
 	resolver := &LinkResolver{
-		pages:   pages,
-		slugMap: make(map[string]*Page),
-		nameMap: make(map[string][]*Page),
+		pages:    pages,
+		slugMap:  make(map[string]*Page),
+		nameMap:  make(map[string][]*Page),
+		aliasMap: make(map[string][]*Page),
+		titleMap: make(map[string][]*Page),
 	}
 
 	for _, page := range pages {
@@ -57,15 +256,49 @@ func NewLinkResolver(pages []*Page) *LinkResolver {
 		slugLower := strings.ToLower(page.Slug)
 		resolver.slugMap[slugLower] = page
 
-		// Map by filename (lowercase)
-		parts := strings.Split(page.Slug, "/")
-		name := strings.ToLower(parts[len(parts)-1])
+		// Map by filename (lowercase), and by the original file name if a
+		// slug override or normalization changed it
+		name := lastSegment(slugLower)
 		resolver.nameMap[name] = append(resolver.nameMap[name], page)
+		if page.SourcePath != "" {
+			if pathName := lastSegment(strings.ToLower(generateSlug(page.SourcePath))); pathName != name {
+				resolver.nameMap[pathName] = append(resolver.nameMap[pathName], page)
+			}
+		}
+
+		// Map by title (lowercase)
+		if title := strings.ToLower(strings.TrimSpace(page.Title)); title != "" {
+			resolver.titleMap[title] = append(resolver.titleMap[title], page)
+		}
+
+		// Map by alias (lowercase)
+		for _, alias := range page.Aliases {
+			aliasLower := strings.ToLower(strings.TrimSpace(alias))
+			if aliasLower != "" {
+				resolver.aliasMap[aliasLower] = append(resolver.aliasMap[aliasLower], page)
+			}
+		}
+	}
+
+	// Map by file path for pages whose slug differs, without shadowing real slugs
+	for _, page := range pages {
+		if page.SourcePath == "" {
+			continue
+		}
+		pathSlug := strings.ToLower(generateSlug(page.SourcePath))
+		if _, taken := resolver.slugMap[pathSlug]; !taken {
+			resolver.slugMap[pathSlug] = page
+		}
 	}
 
 	return resolver
 }
 
+// lastSegment returns the part of a slug after the last slash
+func lastSegment(slug string) string {
+	return slug[strings.LastIndex(slug, "/")+1:]
+}
+
 // ResolveResult represents the result of resolving a wiki-link
 type ResolveResult struct {
 	Page      *Page
@@ -93,10 +326,63 @@ func (r *LinkResolver) Resolve(target string) ResolveResult {
 		}
 	}
 
-	// 3. Broken link
+	// 3. Alias match
+	if pages, ok := r.aliasMap[targetLower]; ok {
+		if len(pages) == 1 {
+			return ResolveResult{Page: pages[0]}
+		}
+		if len(pages) > 1 {
+			// Ambiguous - several pages claim the same alias
+			return ResolveResult{Page: pages[0], Ambiguous: true}
+		}
+	}
+
+	// 4. Title match
+	if pages, ok := r.titleMap[targetLower]; ok {
+		if len(pages) == 1 {
+			return ResolveResult{Page: pages[0]}
+		}
+		if len(pages) > 1 {
+			// Ambiguous - several pages share the title
+			return ResolveResult{Page: pages[0], Ambiguous: true}
+		}
+	}
+
+	// 5. Broken link
 	return ResolveResult{Broken: true}
 }
 
+// SetUnpublished records notes that exist but aren't published, so links to
+// them can render as plain text rather than as broken links
+func (r *LinkResolver) SetUnpublished(pages []*Page) {
+	r.unpublished = nil
+	if len(pages) > 0 {
+		r.unpublished = NewLinkResolver(pages)
+	}
+}
+
+// IsUnpublished reports whether a target that doesn't resolve to a published
+// page names an unpublished note
+func (r *LinkResolver) IsUnpublished(target string) bool {
+	return r.unpublished != nil && !r.unpublished.Resolve(target).Broken
+}
+
+// AmbiguousAliases returns aliases claimed by more than one page, mapped to their pages
+func (r *LinkResolver) AmbiguousAliases() map[string][]*Page {
+	result := make(map[string][]*Page)
+	for alias, pages := range r.aliasMap {
+		if len(pages) > 1 {
+			result[alias] = pages
+		}
+	}
+	return result
+}
+
+// HasAnchor reports whether page defines the heading or block a fragment refers to
+func (r *LinkResolver) HasAnchor(page *Page, fragment string) bool {
+	return r.anchors.get(page)[FragmentAnchor(fragment)]
+}
+
 // BuildBacklinks populates the Backlinks field on all pages
 // If resolver is nil, a new one will be created
 func BuildBacklinks(pages []*Page, resolver ...*LinkResolver) {
@@ -117,6 +403,10 @@ func BuildBacklinks(pages []*Page, resolver ...*LinkResolver) {
 	for _, page := range pages {
 		links := ExtractWikiLinks(page.RawContent)
 		for _, link := range links {
+			// Same-page links ([[#Heading]]) don't create backlinks
+			if link.Target == "" {
+				continue
+			}
 			page.OutLinks = append(page.OutLinks, link.Target)
 		}
 	}
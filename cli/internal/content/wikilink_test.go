@@ -0,0 +1,147 @@
+package content
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// renderNotes writes notes to a temporary vault, then scans and renders them,
+// returning the pages by slug
+func renderNotes(t *testing.T, notes map[string]string, opts RenderOptions) map[string]*Page {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range notes {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages, err := NewScanner(dir, nil).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	RenderPages(pages, NewLinkResolver(pages), opts)
+	bySlug := make(map[string]*Page)
+	for _, p := range pages {
+		bySlug[p.Slug] = p
+	}
+	return bySlug
+}
+
+func TestExtractWikiLinks(t *testing.T) {
+	got := ExtractWikiLinks("See [[Note]], [[ notes/other | Other ]] and [[Note#Heading|here]] or [[#^block]].")
+	want := []WikiLink{
+		{Target: "Note", Label: "Note", Raw: "[[Note]]"},
+		{Target: "notes/other", Label: "Other", Raw: "[[ notes/other | Other ]]"},
+		{Target: "Note", Fragment: "Heading", Label: "here", Raw: "[[Note#Heading|here]]"},
+		{Target: "", Fragment: "^block", Label: "#^block", Raw: "[[#^block]]"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractWikiLinks() =\n%+v\nwant\n%+v", got, want)
+	}
+	if !got[3].IsBlockRef() || got[2].IsBlockRef() {
+		t.Error("IsBlockRef() doesn't match the fragment")
+	}
+}
+
+func TestLinkResolverResolve(t *testing.T) {
+	pages := []*Page{
+		{Slug: "garden", Title: "My Garden", SourcePath: "garden.md"},
+		{Slug: "notes/soil", Title: "Soil", SourcePath: "notes/soil.md", Aliases: []string{"Dirt"}},
+		{Slug: "a/water", Title: "Water A", SourcePath: "a/water.md", Aliases: []string{"H2O"}},
+		{Slug: "b/water", Title: "Water B", SourcePath: "b/water.md", Aliases: []string{"h2o"}},
+		{Slug: "renamed", Title: "Renamed", SourcePath: "Original Name.md"},
+	}
+	resolver := NewLinkResolver(pages)
+
+	tests := []struct {
+		target    string
+		want      *Page
+		ambiguous bool
+	}{
+		{"garden", pages[0], false},
+		{"NOTES/Soil", pages[1], false},
+		{"soil", pages[1], false},
+		{"dirt", pages[1], false},
+		{"my garden", pages[0], false},
+		{"original name", pages[4], false},
+		{"water", pages[2], true},
+		{"h2o", pages[2], true},
+		{"nowhere", nil, false},
+	}
+	for _, tt := range tests {
+		got := resolver.Resolve(tt.target)
+		if got.Page != tt.want || got.Ambiguous != tt.ambiguous || got.Broken != (tt.want == nil) {
+			t.Errorf("Resolve(%q) = %+v, want page %v (ambiguous %v)", tt.target, got, tt.want, tt.ambiguous)
+		}
+	}
+	if aliases := resolver.AmbiguousAliases(); len(aliases) != 1 || len(aliases["h2o"]) != 2 {
+		t.Errorf("AmbiguousAliases() = %v, want h2o claimed twice", aliases)
+	}
+}
+
+func TestRenderWikiLinks(t *testing.T) {
+	pages := renderNotes(t, map[string]string{
+		"garden.md": "---\ntitle: Garden\n---\n## Soil\n\nRich soil. ^rich\n",
+		"links.md": "---\ntitle: Links\n---\n## Intro\n\n" +
+			"[[garden]] [[garden|the garden]] [[garden#Soil]] [[garden#^rich]] [[#Intro]]\n\n" +
+			"[[garden#Nowhere]] [[missing <b>]] [[#Nowhere]] `[[garden]]`\n",
+	}, RenderOptions{Wikilinks: true, BasePath: "/site"})
+	links := pages["links"]
+
+	for _, want := range []string{
+		`<a class="lp-wikilink" href="/site/garden/">garden</a>`,
+		`<a class="lp-wikilink" href="/site/garden/">the garden</a>`,
+		`<a class="lp-wikilink" href="/site/garden/#soil">garden#Soil</a>`,
+		`<a class="lp-wikilink" href="/site/garden/#^rich">garden#^rich</a>`,
+		`<a class="lp-wikilink" href="#intro">#Intro</a>`,
+		`<a class="lp-wikilink" href="/site/garden/#nowhere">garden#Nowhere</a>`,
+		`<span class="lp-broken-link">missing &lt;b&gt;</span>`,
+		`<span class="lp-broken-link">#Nowhere</span>`,
+		`<code>[[garden]]</code>`,
+	} {
+		if !strings.Contains(links.HTMLContent, want) {
+			t.Errorf("links.md doesn't contain %s\n%s", want, links.HTMLContent)
+		}
+	}
+
+	wantWarnings := []string{
+		"missing heading: [[garden#Nowhere]]",
+		"broken link: [[missing <b>]]",
+		"missing heading: [[#Nowhere]] in links.md",
+	}
+	if !reflect.DeepEqual(links.Warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", links.Warnings, wantWarnings)
+	}
+}
+
+func TestRenderWikiLinksDisabled(t *testing.T) {
+	pages := renderNotes(t, map[string]string{
+		"garden.md": "---\ntitle: Garden\n---\nGarden.\n",
+		"links.md":  "---\ntitle: Links\n---\nSee [[garden]].\n",
+	}, RenderOptions{})
+	if got := pages["links"].HTMLContent; !strings.Contains(got, "<p>See [[garden]].</p>") {
+		t.Errorf("wiki-link rendered without Wikilinks: %s", got)
+	}
+}
+
+func TestRenderUnpublishedLink(t *testing.T) {
+	page := &Page{Slug: "links", SourcePath: "links.md"}
+	resolver := NewLinkResolver([]*Page{page})
+	resolver.SetUnpublished([]*Page{{Slug: "private", Title: "Private", SourcePath: "private.md"}})
+
+	html, warnings := NewRenderer(resolver, RenderOptions{Wikilinks: true}).Render("See [[private]] and ![[private]].")
+	if want := "<p>See private and private.</p>"; !strings.Contains(html, want) {
+		t.Errorf("Render() = %s, want %s", html, want)
+	}
+	want := []string{"unpublished link: [[private]]", "unpublished embed: ![[private]]"}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}
@@ -0,0 +1,330 @@
+// Package linkcheck checks that external links still resolve. Requests run
+// concurrently with a limit per host, and results are cached on disk so
+// repeated checks only hit each URL once per TTL.
+package linkcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCacheFile is where results are cached, relative to the site root
+const DefaultCacheFile = ".leafpress/linkcheck.json"
+
+// Options configures a Checker. Zero values use the defaults noted.
+type Options struct {
+	Concurrency int           // Requests in flight across all hosts (default 8)
+	PerHost     int           // Requests in flight per host (default 2)
+	HostDelay   time.Duration // Pause between requests to the same host (default 200ms)
+	Timeout     time.Duration // Per request, including redirects (default 10s)
+	Slow        time.Duration // Responses slower than this are reported as slow (default 3s)
+	CacheFile   string        // JSON file of past results ("" = no cache)
+	CacheTTL    time.Duration // How long cached results are reused (default 24h)
+	Client      *http.Client  // HTTP client (default: a new client)
+}
+
+// Result is the outcome of checking one URL
+type Result struct {
+	URL       string        `json:"url"`
+	Status    int           `json:"status,omitempty"`   // Final HTTP status (0 if the request failed)
+	FinalURL  string        `json:"finalURL,omitempty"` // Where redirects ended (empty if not redirected)
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"` // Network error or timeout
+	CheckedAt time.Time     `json:"checkedAt"`
+	Cached    bool          `json:"-"` // Result came from the cache
+}
+
+// Dead reports whether the link failed or returned an error status. A rate
+// limited link isn't dead: the server only asked to be tried again later.
+func (r Result) Dead() bool {
+	return r.Error != "" || (r.Status >= 400 && !r.RateLimited())
+}
+
+// RateLimited reports whether the server refused the request as too many (HTTP 429)
+func (r Result) RateLimited() bool {
+	return r.Status == http.StatusTooManyRequests
+}
+
+// Redirected reports whether the link ended up at a different URL
+func (r Result) Redirected() bool {
+	return r.FinalURL != "" && r.FinalURL != r.URL
+}
+
+// Checker checks external URLs
+type Checker struct {
+	opts   Options
+	client *http.Client
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+	cache map[string]Result // URL -> last result
+}
+
+// hostLimiter caps concurrent requests to a host and spaces them out
+type hostLimiter struct {
+	slots chan struct{}
+	mu    sync.Mutex
+	next  time.Time // Earliest time the next request may start
+}
+
+// New creates a Checker, loading the cache file if there is one
+func New(opts Options) *Checker {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 8
+	}
+	if opts.PerHost < 1 {
+		opts.PerHost = 2
+	}
+	if opts.HostDelay == 0 {
+		opts.HostDelay = 200 * time.Millisecond
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Slow == 0 {
+		opts.Slow = 3 * time.Second
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = 24 * time.Hour
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	c := &Checker{
+		opts:   opts,
+		client: client,
+		hosts:  make(map[string]*hostLimiter),
+		cache:  make(map[string]Result),
+	}
+	c.loadCache()
+	return c
+}
+
+// Slow reports whether a result took longer than the slow threshold
+func (c *Checker) Slow(r Result) bool {
+	return r.Error == "" && r.Duration > c.opts.Slow
+}
+
+// Check checks each distinct URL once, reusing cached results younger than the TTL
+func (c *Checker) Check(ctx context.Context, urls []string) map[string]Result {
+	results := make(map[string]Result, len(urls))
+	var pending []string
+	for _, u := range urls {
+		if _, seen := results[u]; seen {
+			continue
+		}
+		if cached, ok := c.cached(u); ok {
+			results[u] = cached
+			continue
+		}
+		results[u] = Result{} // Placeholder, so duplicates are skipped
+		pending = append(pending, u)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	workers := c.opts.Concurrency
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				result := c.check(ctx, u)
+				mu.Lock()
+				results[u] = result
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, u := range pending {
+		jobs <- u
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Network errors and rate limits are temporary, so only other responses are cached
+	c.mu.Lock()
+	for _, u := range pending {
+		if results[u].Error == "" && !results[u].RateLimited() {
+			c.cache[u] = results[u]
+		}
+	}
+	c.mu.Unlock()
+
+	return results
+}
+
+// check requests one URL: HEAD first, then GET for servers that don't support HEAD
+func (c *Checker) check(ctx context.Context, rawURL string) Result {
+	result := Result{URL: rawURL, CheckedAt: time.Now()}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		result.Error = "not an http(s) URL"
+		return result
+	}
+
+	limiter := c.host(parsed.Host)
+	if err := limiter.acquire(ctx, c.opts.HostDelay); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer limiter.release()
+
+	// The duration is that of the request the result comes from
+	start := time.Now()
+	resp, err := c.request(ctx, http.MethodHead, rawURL)
+	if err != nil || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusForbidden {
+		start = time.Now()
+		resp, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = resp.StatusCode
+	if final := resp.Request.URL.String(); final != rawURL {
+		result.FinalURL = final
+	}
+	return result
+}
+
+// request sends a single request with the checker's timeout, discarding the body
+func (c *Checker) request(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "leafpress-linkcheck/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// Only the status matters; don't download whole pages
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	return resp, nil
+}
+
+// host returns the limiter for a host
+func (c *Checker) host(name string) *hostLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	limiter, ok := c.hosts[name]
+	if !ok {
+		limiter = &hostLimiter{slots: make(chan struct{}, c.opts.PerHost)}
+		c.hosts[name] = limiter
+	}
+	return limiter
+}
+
+// acquire waits for a free slot and for the delay since the previous request
+func (h *hostLimiter) acquire(ctx context.Context, delay time.Duration) error {
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(delay)
+	h.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(start)):
+		return nil
+	case <-ctx.Done():
+		<-h.slots
+		return ctx.Err()
+	}
+}
+
+func (h *hostLimiter) release() {
+	<-h.slots
+}
+
+// cached returns a cached result if it is younger than the TTL
+func (c *Checker) cached(u string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.cache[u]
+	if !ok || c.opts.CacheFile == "" || time.Since(result.CheckedAt) > c.opts.CacheTTL {
+		return Result{}, false
+	}
+	result.Cached = true
+	return result, true
+}
+
+// loadCache reads the cache file, ignoring a missing or corrupt file
+func (c *Checker) loadCache() {
+	if c.opts.CacheFile == "" {
+		return
+	}
+	data, err := os.ReadFile(c.opts.CacheFile)
+	if err != nil {
+		return
+	}
+	var entries []Result
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	for _, entry := range entries {
+		c.cache[entry.URL] = entry
+	}
+}
+
+// SaveCache writes results younger than the TTL to the cache file
+func (c *Checker) SaveCache() error {
+	if c.opts.CacheFile == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	var entries []Result
+	for _, result := range c.cache {
+		if time.Since(result.CheckedAt) <= c.opts.CacheTTL {
+			entries = append(entries, result)
+		}
+	}
+	c.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode link cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.opts.CacheFile), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.opts.CacheFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write link cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestChecker returns a checker without a cache or delays between requests
+func newTestChecker(opts Options) *Checker {
+	if opts.HostDelay == 0 {
+		opts.HostDelay = time.Nanosecond
+	}
+	return New(opts)
+}
+
+func TestCheckStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path        string
+		status      int
+		dead        bool
+		rateLimited bool
+		redirected  bool
+	}{
+		{"/ok", 200, false, false, false},
+		{"/missing", 404, true, false, false},
+		{"/busy", 429, false, true, false},
+		{"/moved", 200, false, false, true},
+	}
+
+	checker := newTestChecker(Options{})
+	var urls []string
+	for _, tt := range tests {
+		urls = append(urls, server.URL+tt.path)
+	}
+	results := checker.Check(context.Background(), urls)
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := results[server.URL+tt.path]
+			if result.Status != tt.status {
+				t.Errorf("Status = %d, want %d", result.Status, tt.status)
+			}
+			if result.Dead() != tt.dead {
+				t.Errorf("Dead() = %v, want %v", result.Dead(), tt.dead)
+			}
+			if result.RateLimited() != tt.rateLimited {
+				t.Errorf("RateLimited() = %v, want %v", result.RateLimited(), tt.rateLimited)
+			}
+			if result.Redirected() != tt.redirected {
+				t.Errorf("Redirected() = %v, want %v", result.Redirected(), tt.redirected)
+			}
+		})
+	}
+	if got := results[server.URL+"/moved"].FinalURL; got != server.URL+"/ok" {
+		t.Errorf("FinalURL = %q, want %q", got, server.URL+"/ok")
+	}
+}
+
+func TestCheckFallsBackToGet(t *testing.T) {
+	for _, status := range []int{http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusForbidden} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			var methods []string
+			var mu sync.Mutex
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				methods = append(methods, r.Method)
+				mu.Unlock()
+				if r.Method == http.MethodHead {
+					w.WriteHeader(status)
+				}
+			}))
+			defer server.Close()
+
+			result := newTestChecker(Options{}).Check(context.Background(), []string{server.URL})[server.URL]
+			if result.Status != http.StatusOK {
+				t.Errorf("Status = %d, want 200", result.Status)
+			}
+			if len(methods) != 2 || methods[0] != http.MethodHead || methods[1] != http.MethodGet {
+				t.Errorf("requests = %v, want [HEAD GET]", methods)
+			}
+		})
+	}
+}
+
+func TestCheckDurationIsFallbackRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			time.Sleep(300 * time.Millisecond)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	checker := newTestChecker(Options{Slow: 200 * time.Millisecond})
+	result := checker.Check(context.Background(), []string{server.URL})[server.URL]
+	if result.Duration >= 300*time.Millisecond {
+		t.Errorf("Duration = %v, want only the GET request timed", result.Duration)
+	}
+	if checker.Slow(result) {
+		t.Error("fast GET after a slow HEAD reported as slow")
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	result := newTestChecker(Options{Timeout: 50 * time.Millisecond}).Check(context.Background(), []string{server.URL})[server.URL]
+	if result.Error == "" || !result.Dead() {
+		t.Errorf("result = %+v, want a timeout error", result)
+	}
+}
+
+func TestCheckConcurrencyLimits(t *testing.T) {
+	var total, maxTotal atomic.Int32
+	newServer := func(maxHost *atomic.Int32) *httptest.Server {
+		var inFlight atomic.Int32
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			updateMax(maxHost, inFlight.Add(1))
+			updateMax(&maxTotal, total.Add(1))
+			time.Sleep(20 * time.Millisecond)
+			inFlight.Add(-1)
+			total.Add(-1)
+		}))
+	}
+	var maxA, maxB atomic.Int32
+	a, b := newServer(&maxA), newServer(&maxB)
+	defer a.Close()
+	defer b.Close()
+
+	var urls []string
+	for i := 0; i < 8; i++ {
+		urls = append(urls, a.URL+"/"+strconv.Itoa(i), b.URL+"/"+strconv.Itoa(i))
+	}
+	results := newTestChecker(Options{Concurrency: 3, PerHost: 2}).Check(context.Background(), urls)
+
+	for _, u := range urls {
+		if results[u].Status != http.StatusOK {
+			t.Errorf("%s: Status = %d, want 200", u, results[u].Status)
+		}
+	}
+	if maxA.Load() > 2 || maxB.Load() > 2 {
+		t.Errorf("max requests per host = %d and %d, want at most 2", maxA.Load(), maxB.Load())
+	}
+	if maxTotal.Load() > 3 {
+		t.Errorf("max requests in flight = %d, want at most 3", maxTotal.Load())
+	}
+}
+
+// updateMax raises max to n if n is larger
+func updateMax(max *atomic.Int32, n int32) {
+	for {
+		current := max.Load()
+		if n <= current || max.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) { requests.Add(1) })
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "linkcheck.json")
+	urls := []string{server.URL + "/ok", server.URL + "/ok", server.URL + "/busy"}
+
+	first := newTestChecker(Options{CacheFile: cacheFile})
+	first.Check(context.Background(), urls)
+	if err := first.SaveCache(); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("first check made %d requests, want 2", got)
+	}
+
+	// A new checker reuses the cached 200 but asks again after a 429
+	results := newTestChecker(Options{CacheFile: cacheFile}).Check(context.Background(), urls)
+	if !results[server.URL+"/ok"].Cached {
+		t.Error("200 result wasn't reused from the cache")
+	}
+	if results[server.URL+"/busy"].Cached {
+		t.Error("429 result was cached")
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("second check made %d requests, want 1", got-2)
+	}
+}
+
+func TestCheckRejectsNonHTTP(t *testing.T) {
+	result := newTestChecker(Options{}).Check(context.Background(), []string{"ftp://example.com/file"})["ftp://example.com/file"]
+	if result.Error == "" {
+		t.Error("ftp URL wasn't rejected")
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/shivamx96/leafpress/cli/internal/build"
 	"github.com/shivamx96/leafpress/cli/internal/config"
+	"github.com/shivamx96/leafpress/cli/internal/content"
 )
 
 // Options configures the server
@@ -36,6 +37,11 @@ type Server struct {
 
 	// File watcher
 	watcher *fsnotify.Watcher
+
+	// Directory of the configured theme, which may be outside the site
+	themeDir string
+	// Guards cfg and themeDir, which change when the config is reloaded
+	mu sync.Mutex
 }
 
 // New creates a new development server
@@ -83,6 +89,7 @@ func (s *Server) Start() error {
 	if err := s.addWatchDirs(cwd); err != nil {
 		return fmt.Errorf("failed to set up file watching: %w", err)
 	}
+	s.watchTheme()
 
 	// Set up HTTP handlers
 	mux := http.NewServeMux()
@@ -91,8 +98,7 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/_lr", s.handleWebSocket)
 
 	// Serve static files with live reload injection
-	outputDir := filepath.Join(cwd, s.cfg.OutputDir)
-	mux.HandleFunc("/", s.handleStatic(outputDir))
+	mux.HandleFunc("/", s.handleStatic(cwd))
 
 	server := &http.Server{
 		Handler: mux,
@@ -113,9 +119,13 @@ func (s *Server) Start() error {
 	return server.Serve(listener)
 }
 
-// handleStatic serves static files with live reload script injection
-func (s *Server) handleStatic(root string) http.HandlerFunc {
+// handleStatic serves the output directory of the site in cwd with live
+// reload script injection
+func (s *Server) handleStatic(cwd string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// The output directory can change when the config is reloaded
+		root := filepath.Join(cwd, s.config().OutputDir)
+
 		// Disable caching for development
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 		w.Header().Set("Pragma", "no-cache")
@@ -307,11 +317,26 @@ func (s *Server) watchFiles() {
 				relPath = event.Name
 			}
 
+			// Watch directories created while running, like a new layouts/partials.
+			// Files may already be inside (moved in, or written before the watch).
+			isDir := false
+			if changeType == build.ChangeCreate {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					isDir = true
+					s.addWatchDirs(event.Name)
+				}
+			}
+
 			// Check if it's a file we care about
 			ext := filepath.Ext(event.Name)
 			base := filepath.Base(event.Name)
 			isStaticFile := strings.HasPrefix(relPath, "static"+string(filepath.Separator)) || relPath == "static"
-			if ext != ".md" && ext != ".css" && base != "leafpress.json" && !isStaticFile {
+			isLayout := (relPath == "layouts" || strings.HasPrefix(relPath, "layouts"+string(filepath.Separator))) && (ext == ".html" || isDir)
+			isTheme := s.inTheme(event.Name)
+			isFont := strings.HasPrefix(relPath, "fonts"+string(filepath.Separator))
+			cfg := s.config()
+			isAttachment := content.IsAttachmentPath(relPath, append([]string{cfg.OutputDir}, cfg.Ignore...))
+			if ext != ".md" && ext != ".css" && base != "leafpress.json" && !isStaticFile && !isLayout && !isTheme && !isFont && !isAttachment {
 				continue
 			}
 
@@ -383,6 +408,9 @@ func (s *Server) rebuildIncremental(changedPath string, changeType build.ChangeT
 		fmt.Printf("Build error: %v\n", err)
 		return
 	}
+	if stats.FullRebuild {
+		s.reloadConfig()
+	}
 
 	elapsed := time.Since(start)
 	if stats.FullRebuild {
@@ -404,8 +432,67 @@ func (s *Server) rebuildIncremental(changedPath string, changeType build.ChangeT
 	}
 }
 
+// config returns the current site config
+func (s *Server) config() *config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// reloadConfig picks up the config the builder reloaded on a full rebuild,
+// updating the watched paths: a new output directory stops being watched,
+// and a new theme starts being watched
+func (s *Server) reloadConfig() {
+	cfg := s.builder.Config()
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+
+	cwd, _ := os.Getwd()
+	outputDir := filepath.Join(cwd, cfg.OutputDir)
+	for _, path := range s.watcher.WatchList() {
+		if isWithin(outputDir, path) {
+			s.watcher.Remove(path)
+		}
+	}
+	s.watchTheme()
+}
+
+// watchTheme starts watching the configured theme if it's outside the site,
+// which addWatchDirs already covers
+func (s *Server) watchTheme() {
+	themeDir := s.builder.ThemeDir()
+	s.mu.Lock()
+	changed := themeDir != s.themeDir
+	s.themeDir = themeDir
+	s.mu.Unlock()
+
+	cwd, _ := os.Getwd()
+	if !changed || themeDir == "" || isWithin(cwd, themeDir) {
+		return
+	}
+	if err := s.addWatchDirs(themeDir); err != nil && s.opts.Verbose {
+		log.Printf("Failed to watch theme %s: %v", themeDir, err)
+	}
+}
+
+// inTheme reports whether a path is inside the configured theme
+func (s *Server) inTheme(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.themeDir != "" && isWithin(s.themeDir, path)
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // addWatchDirs recursively adds directories to the watcher
 func (s *Server) addWatchDirs(root string) error {
+	cwd, _ := os.Getwd()
+	outputDir := filepath.Join(cwd, s.config().OutputDir)
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil
@@ -419,7 +506,7 @@ func (s *Server) addWatchDirs(root string) error {
 		// Skip output and hidden directories
 		name := info.Name()
 		if name == "_site" || name == ".leafpress" || name == ".git" ||
-			name == "node_modules" || name == ".obsidian" {
+			name == "node_modules" || name == ".obsidian" || path == outputDir {
 			return filepath.SkipDir
 		}
 
@@ -332,6 +332,17 @@ html {
   margin-right: 0.5rem;
 }
 
+.lp-reading-time + .lp-param::before,
+.lp-date-info + .lp-param::before,
+.lp-param + .lp-param::before {
+  content: "·";
+  margin-right: 0.5rem;
+}
+
+.lp-param-label {
+  font-weight: 500;
+}
+
 .lp-date,
 .lp-modified {
   color: var(--lp-text-muted);
@@ -553,6 +564,13 @@ html {
   border-radius: 4px;
 }
 
+.lp-content mark {
+  background: color-mix(in srgb, var(--lp-accent) 30%, transparent);
+  color: inherit;
+  padding: 0 0.1em;
+  border-radius: 2px;
+}
+
 .lp-content hr {
   border: none;
   border-top: 1px solid var(--lp-border);
@@ -640,6 +658,16 @@ html {
   content: "";
 }
 
+/* Block reference targets (^block-id) */
+.lp-block-anchor {
+  scroll-margin-top: calc(var(--lp-nav-height) + 1rem);
+}
+
+:has(> .lp-block-anchor:target) {
+  background: var(--lp-code-bg);
+  border-radius: 4px;
+}
+
 /* Knowledge Graph */
 .lp-graph-node {
   transition: all 0.2s ease;
@@ -976,6 +1004,61 @@ html {
   font-size: 0.85rem;
 }
 
+/* Query blocks */
+.lp-query-link {
+  color: var(--lp-accent);
+  text-decoration: none;
+}
+
+.lp-query-link:hover {
+  text-decoration: underline;
+}
+
+.lp-query-empty,
+.lp-query-error {
+  color: var(--lp-text-muted);
+  font-style: italic;
+}
+
+.lp-query-error {
+  border-left: 3px solid #dc2626;
+  padding-left: 0.75rem;
+}
+
+/* Nested tags */
+.lp-tag-tree {
+  list-style: none;
+  padding: 0;
+  margin: 0;
+}
+
+.lp-tag-tree-item {
+  padding: 0.2rem 0 0.2rem calc(var(--lp-tag-depth, 0) * 1.25rem);
+}
+
+.lp-tag-breadcrumbs {
+  color: var(--lp-text-muted);
+  font-size: 0.85rem;
+  margin-bottom: 0.5rem;
+}
+
+.lp-tag-breadcrumbs a {
+  color: var(--lp-text-muted);
+  text-decoration: none;
+}
+
+.lp-tag-breadcrumbs a:hover {
+  color: var(--lp-accent);
+}
+
+.lp-tag-breadcrumbs-sep {
+  margin: 0 0.25rem;
+}
+
+.lp-tag-children {
+  margin-bottom: 1.5rem;
+}
+
 /* Footer */
 .lp-footer {
   border-top: 1px solid var(--lp-border);
@@ -1470,6 +1553,37 @@ html {
   margin-bottom: 0;
 }
 
+.lp-callout .lp-callout {
+  margin: 0.75rem 0;
+}
+
+/* Foldable callouts */
+summary.lp-callout-title {
+  cursor: pointer;
+  list-style: none;
+  user-select: none;
+}
+
+summary.lp-callout-title::-webkit-details-marker {
+  display: none;
+}
+
+summary.lp-callout-title::after {
+  content: "›";
+  margin-left: auto;
+  font-size: 1.125rem;
+  line-height: 1;
+  transition: transform 0.15s ease;
+}
+
+details.lp-callout[open] > summary.lp-callout-title::after {
+  transform: rotate(90deg);
+}
+
+details.lp-callout:not([open]) > .lp-callout-title {
+  margin-bottom: 0;
+}
+
 /* Callout type colors - subtle borders, light backgrounds */
 .lp-callout-note {
   --lp-callout-border: rgba(59, 130, 246, 0.3);
@@ -1618,4 +1732,106 @@ html {
   --lp-callout-bg: rgba(6, 182, 212, 0.08);
   --lp-callout-title: #22d3ee;
 }
+
+/* Embedded notes (![[note]]) */
+.lp-embed {
+  margin: 1.5rem 0;
+  padding: 0.875rem 1rem;
+  border-left: 3px solid var(--lp-accent);
+  border-radius: 0 6px 6px 0;
+  background: var(--lp-code-bg);
+}
+
+.lp-embed-title {
+  font-size: 0.8125rem;
+  font-weight: 500;
+  margin-bottom: 0.5rem;
+}
+
+.lp-embed-content > :first-child {
+  margin-top: 0;
+}
+
+.lp-embed-content > :last-child {
+  margin-bottom: 0;
+}
+
+.lp-embed-error {
+  color: var(--lp-text-muted);
+  font-size: 0.875rem;
+  font-style: italic;
+}
+
+/* Math (MathML rendered at build time) */
+.lp-math-display {
+  display: block;
+  margin: 1.25rem 0;
+  overflow-x: auto;
+  overflow-y: hidden;
+}
+
+.lp-math-display math {
+  display: block math;
+}
+
+/* Shortcodes ({{< figure >}}, {{< details >}}, {{< video >}}, {{< youtube-nocookie >}}) */
+.lp-figure {
+  margin: 1.5rem 0;
+}
+
+.lp-figure img {
+  display: block;
+}
+
+.lp-figure figcaption {
+  margin-top: 0.5rem;
+  color: var(--lp-text-muted);
+  font-size: 0.875rem;
+}
+
+.lp-figure figcaption > :first-child {
+  margin-top: 0;
+}
+
+.lp-figure figcaption > :last-child {
+  margin-bottom: 0;
+}
+
+.lp-details {
+  margin: 1.5rem 0;
+  padding: 0.75rem 1rem;
+  border: 1px solid var(--lp-border);
+  border-radius: 6px;
+}
+
+.lp-details summary {
+  cursor: pointer;
+  font-weight: 500;
+}
+
+.lp-details-content > :last-child {
+  margin-bottom: 0;
+}
+
+.lp-video {
+  display: block;
+  max-width: 100%;
+  margin: 1.5rem 0;
+  border-radius: 4px;
+}
+
+.lp-video-embed {
+  position: relative;
+  margin: 1.5rem 0;
+  aspect-ratio: 16 / 9;
+}
+
+.lp-video-embed iframe {
+  position: absolute;
+  inset: 0;
+  width: 100%;
+  height: 100%;
+  border: 0;
+  border-radius: 4px;
+}
 `
@@ -0,0 +1,126 @@
+package templates
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// layoutTemplates lists the templates a layouts directory can override, by file name
+var layoutTemplates = []string{"base.html", "page.html", "index.html", "tags.html", "tag.html", "404.html", "redirect.html"}
+
+// layoutFile is a template file from a layouts directory
+type layoutFile struct {
+	name string // Shown in errors (e.g., "layouts/page.html")
+	text string
+}
+
+// layoutDir holds the overrides found in one layouts directory
+type layoutDir struct {
+	templates map[string]layoutFile // Whole templates, by file name (e.g., "page.html")
+	partials  []layoutFile          // partials/<block>.html, applied to every page template
+}
+
+// loadLayoutDir reads a layouts directory. Returns nil if it doesn't exist.
+func loadLayoutDir(dir string) (*layoutDir, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layouts: %w", err)
+	}
+
+	layouts := &layoutDir{templates: make(map[string]layoutFile)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".html" {
+			continue
+		}
+		if !slices.Contains(layoutTemplates, name) {
+			return nil, fmt.Errorf("unknown layout %s (expected one of %s, or a block in partials/)",
+				displayPath(filepath.Join(dir, name)), strings.Join(layoutTemplates, ", "))
+		}
+		file, err := readLayoutFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		layouts.templates[name] = file
+	}
+
+	partials, err := filepath.Glob(filepath.Join(dir, "partials", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(partials)
+	for _, p := range partials {
+		file, err := readLayoutFile(p)
+		if err != nil {
+			return nil, err
+		}
+		layouts.partials = append(layouts.partials, file)
+	}
+
+	return layouts, nil
+}
+
+func readLayoutFile(path string) (layoutFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return layoutFile{}, fmt.Errorf("failed to read layout: %w", err)
+	}
+	return layoutFile{name: displayPath(path), text: string(data)}, nil
+}
+
+// displayPath shortens a path to be relative to the working directory, for errors
+func displayPath(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// overlay parses a layout file into t. Its {{define}}s replace blocks of the
+// same name, and any content outside them replaces the template named target.
+// Templates are named after the file, so errors point at its lines.
+func overlay(t *template.Template, file layoutFile, target string) error {
+	added, err := t.New(file.name).Parse(file.text)
+	if err != nil {
+		return err
+	}
+	if added.Tree == nil || parse.IsEmptyTree(added.Tree.Root) {
+		return nil
+	}
+	if _, err := t.AddParseTree(target, added.Tree.Copy()); err != nil {
+		return fmt.Errorf("%s: %w", file.name, err)
+	}
+	return nil
+}
+
+// overlayPage applies the partials and the page-type layout of each
+// directory, in order, to a page template
+func overlayPage(t *template.Template, dirs []*layoutDir, fileName string) error {
+	for _, dir := range dirs {
+		for _, partial := range dir.partials {
+			block := strings.TrimSuffix(filepath.Base(partial.name), ".html")
+			if err := overlay(t, partial, block); err != nil {
+				return err
+			}
+		}
+		if file, ok := dir.templates[fileName]; ok {
+			if err := overlay(t, file, "base"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,887 @@
+package templates
+
+import (
+	"io"
+	"text/template"
+)
+
+// scriptTemplate is parsed once from scriptSource
+var scriptTemplate = template.Must(template.New("script").Parse(scriptSource))
+
+// RenderScript renders the site script, once per build
+func RenderScript(w io.Writer, site SiteData) error {
+	return scriptTemplate.Execute(w, site)
+}
+
+// scriptSource is the site script. Its conditions depend only on site
+// settings, so every page shares one copy.
+const scriptSource = `// Add copy buttons to code blocks
+document.addEventListener('DOMContentLoaded', function() {
+  // Theme toggle
+  var themeToggle = document.querySelector('.lp-theme-toggle');
+  if (themeToggle) {
+    themeToggle.addEventListener('click', function() {
+      var currentTheme = document.documentElement.getAttribute('data-theme') || 'light';
+      var newTheme = currentTheme === 'light' ? 'dark' : 'light';
+      document.documentElement.setAttribute('data-theme', newTheme);
+      localStorage.setItem('theme', newTheme);
+
+      // Update graph colors if graph exists
+      var graphBody = document.getElementById('lp-graph-panel-body');
+      if (graphBody && graphBody.querySelector('svg')) {
+        var isDark = newTheme === 'dark';
+        var linkColor = isDark ? '#444444' : '#d0d0d0';
+        var textColor = getComputedStyle(document.documentElement).getPropertyValue('--lp-text').trim();
+        var accentColor = getComputedStyle(document.documentElement).getPropertyValue('--lp-accent').trim();
+        graphBody.querySelectorAll('.lp-graph-link').forEach(function(link) {
+          if (!link.style.opacity || link.style.opacity === '0.5') {
+            link.setAttribute('stroke', linkColor);
+          }
+        });
+        graphBody.querySelectorAll('.lp-graph-label').forEach(function(label) {
+          label.style.fill = textColor;
+        });
+        graphBody.querySelectorAll('.lp-graph-node').forEach(function(node) {
+          node.setAttribute('fill', accentColor);
+        });
+      }
+    });
+  }
+
+  {{if eq .Theme.NavStyle "glassy"}}
+  // Floating pill navbar on scroll
+  var nav = document.querySelector('.lp-nav');
+  var navPlaceholder = document.querySelector('.lp-nav-placeholder');
+  if (nav && navPlaceholder) {
+    var navHeight = nav.offsetHeight;
+    navPlaceholder.style.height = navHeight + 'px';
+
+    window.addEventListener('scroll', function() {
+      if (window.scrollY > navHeight) {
+        nav.classList.add('lp-nav--pill');
+        navPlaceholder.classList.add('lp-nav-placeholder--active');
+      } else {
+        nav.classList.remove('lp-nav--pill');
+        navPlaceholder.classList.remove('lp-nav-placeholder--active');
+      }
+    });
+  }
+  {{end}}
+
+  // Copy buttons
+  document.querySelectorAll('pre.chroma').forEach(function(pre) {
+    var button = document.createElement('button');
+    button.className = 'lp-copy-button';
+    button.textContent = 'Copy';
+    button.setAttribute('aria-label', 'Copy code to clipboard');
+
+    button.addEventListener('click', function() {
+      var code = pre.querySelector('code').textContent;
+      navigator.clipboard.writeText(code).then(function() {
+        button.textContent = 'Copied!';
+        setTimeout(function() {
+          button.textContent = 'Copy';
+        }, 2000);
+      }).catch(function() {
+        button.textContent = 'Failed';
+        setTimeout(function() {
+          button.textContent = 'Copy';
+        }, 2000);
+      });
+    });
+
+    pre.style.position = 'relative';
+    pre.appendChild(button);
+  });
+  {{if .Graph}}
+  // Graph Overlay
+  (function() {
+    var overlay = document.getElementById('lp-graph-overlay');
+    var panel = overlay.querySelector('.lp-graph-panel');
+    var graphBody = document.getElementById('lp-graph-panel-body');
+    var toggleBtn = document.querySelector('.lp-graph-toggle');
+    var closeBtn = overlay.querySelector('.lp-graph-close');
+    var backdrop = overlay.querySelector('.lp-graph-backdrop');
+    var currentSlug = panel.getAttribute('data-current-slug') || '';
+    var graphData = null;
+    var graphRendered = false;
+
+    function openGraph() {
+      overlay.classList.add('lp-graph-overlay--open');
+      overlay.setAttribute('aria-hidden', 'false');
+      document.body.style.overflow = 'hidden';
+
+      if (!graphRendered && graphData) {
+        renderGraph(graphData);
+        graphRendered = true;
+      } else if (!graphData) {
+        fetch(LP_BASE_PATH + '/graph.json')
+          .then(function(r) { return r.json(); })
+          .then(function(data) {
+            graphData = data;
+            renderGraph(data);
+            graphRendered = true;
+          });
+      }
+    }
+
+    function closeGraph() {
+      overlay.classList.remove('lp-graph-overlay--open');
+      overlay.setAttribute('aria-hidden', 'true');
+      document.body.style.overflow = '';
+    }
+
+    toggleBtn.addEventListener('click', openGraph);
+    closeBtn.addEventListener('click', closeGraph);
+    backdrop.addEventListener('click', closeGraph);
+
+    document.addEventListener('keydown', function(e) {
+      if (e.key === 'Escape' && overlay.classList.contains('lp-graph-overlay--open')) {
+        closeGraph();
+      }
+    });
+
+    function renderGraph(data) {
+      var width = graphBody.offsetWidth;
+      var height = graphBody.offsetHeight;
+
+      var svg = document.createElementNS('http://www.w3.org/2000/svg', 'svg');
+      svg.setAttribute('width', width);
+      svg.setAttribute('height', height);
+      svg.setAttribute('viewBox', '0 0 ' + width + ' ' + height);
+      graphBody.appendChild(svg);
+
+      // Pass 1: Group nodes by primary tag for initial placement
+      var tagGroups = {};
+      var untaggedNodes = [];
+      data.nodes.forEach(function(d) {
+        var primaryTag = (d.tags && d.tags.length > 0) ? d.tags[0] : null;
+        if (primaryTag) {
+          if (!tagGroups[primaryTag]) tagGroups[primaryTag] = [];
+          tagGroups[primaryTag].push(d);
+        } else {
+          untaggedNodes.push(d);
+        }
+      });
+
+      // Assign positions by tag group (arrange in sectors around center)
+      var tagNames = Object.keys(tagGroups);
+      var numGroups = tagNames.length;
+      var centerX = width / 2;
+      var centerY = height / 2;
+      var radius = Math.min(width, height) * 0.3;
+
+      var nodes = [];
+      tagNames.forEach(function(tag, groupIndex) {
+        var angle = (2 * Math.PI * groupIndex) / numGroups;
+        var groupCenterX = centerX + radius * Math.cos(angle);
+        var groupCenterY = centerY + radius * Math.sin(angle);
+        var groupNodes = tagGroups[tag];
+
+        groupNodes.forEach(function(d, i) {
+          // Spread nodes within group
+          var spread = 50;
+          var offsetAngle = (2 * Math.PI * i) / groupNodes.length;
+          nodes.push({
+            id: d.id,
+            title: d.title,
+            url: d.url,
+            tags: d.tags || [],
+            x: groupCenterX + spread * Math.cos(offsetAngle) * (0.5 + Math.random() * 0.5),
+            y: groupCenterY + spread * Math.sin(offsetAngle) * (0.5 + Math.random() * 0.5),
+            vx: 0,
+            vy: 0
+          });
+        });
+      });
+
+      // Untagged nodes go near center with some randomness
+      untaggedNodes.forEach(function(d) {
+        nodes.push({
+          id: d.id,
+          title: d.title,
+          url: d.url,
+          tags: d.tags || [],
+          x: centerX + (Math.random() - 0.5) * 100,
+          y: centerY + (Math.random() - 0.5) * 100,
+          vx: 0,
+          vy: 0
+        });
+      });
+
+      var nodeMap = {};
+      nodes.forEach(function(n) { nodeMap[n.id] = n; });
+
+      var links = [];
+      data.edges.forEach(function(edge) {
+        var source = nodeMap[edge.source];
+        var target = nodeMap[edge.target];
+        if (source && target) {
+          links.push({ source: source, target: target, sourceId: edge.source, targetId: edge.target });
+        }
+      });
+
+      // Calculate node degrees and build adjacency list for clustering
+      nodes.forEach(function(n) {
+        n.degree = 0;
+        n.neighbors = [];
+      });
+      links.forEach(function(link) {
+        link.source.degree++;
+        link.target.degree++;
+        link.source.neighbors.push(link.target);
+        link.target.neighbors.push(link.source);
+      });
+      var maxDegree = Math.max.apply(null, nodes.map(function(n) { return n.degree; })) || 1;
+
+      // Check if two nodes share neighbors (for clustering)
+      function shareNeighbors(a, b) {
+        for (var i = 0; i < a.neighbors.length; i++) {
+          if (b.neighbors.indexOf(a.neighbors[i]) !== -1) return true;
+        }
+        return false;
+      }
+
+      // Check if two nodes are directly connected
+      function areConnected(a, b) {
+        return a.neighbors.indexOf(b) !== -1;
+      }
+
+      // Count shared tags between two nodes (for tag-based clustering)
+      function sharedTagCount(a, b) {
+        var count = 0;
+        for (var i = 0; i < a.tags.length; i++) {
+          if (b.tags.indexOf(a.tags[i]) !== -1) count++;
+        }
+        return count;
+      }
+
+      // Centrality score: normalized degree (0-1)
+      function getCentrality(node) {
+        return node.degree / maxDegree;
+      }
+
+      var linkGroup = document.createElementNS('http://www.w3.org/2000/svg', 'g');
+      svg.appendChild(linkGroup);
+
+      var nodeGroup = document.createElementNS('http://www.w3.org/2000/svg', 'g');
+      svg.appendChild(nodeGroup);
+
+      var labelGroup = document.createElementNS('http://www.w3.org/2000/svg', 'g');
+      svg.appendChild(labelGroup);
+
+      var isDark = document.documentElement.getAttribute('data-theme') === 'dark';
+      var linkColor = isDark ? '#444444' : '#d0d0d0';
+      var accentColor = getComputedStyle(document.documentElement).getPropertyValue('--lp-accent').trim();
+
+      links.forEach(function(link) {
+        var line = document.createElementNS('http://www.w3.org/2000/svg', 'line');
+        line.setAttribute('class', 'lp-graph-link');
+        line.setAttribute('stroke', linkColor);
+        line.setAttribute('stroke-width', '1.5');
+        line.setAttribute('stroke-opacity', '0.5');
+        linkGroup.appendChild(line);
+        link.element = line;
+      });
+
+      var selectedNode = null;
+
+      // Node opacity based on link density (degree)
+      function getNodeOpacity(degree) {
+        // More connections = more opaque (0.15 to 1.0 for better contrast)
+        return 0.15 + (degree / maxDegree) * 0.85;
+      }
+
+      nodes.forEach(function(node) {
+        var circle = document.createElementNS('http://www.w3.org/2000/svg', 'circle');
+        circle.setAttribute('class', 'lp-graph-node');
+        circle.setAttribute('r', '6');
+        circle.setAttribute('fill', accentColor);
+        circle.setAttribute('fill-opacity', getNodeOpacity(node.degree));
+        circle.setAttribute('stroke', '#fff');
+        circle.setAttribute('stroke-width', '2');
+        circle.style.cursor = 'pointer';
+
+        // Mark current page node
+        if (node.id === currentSlug) {
+          circle.classList.add('lp-graph-node--current');
+        }
+
+        // Hover for preview highlight
+        circle.addEventListener('mouseenter', function() {
+          if (!selectedNode) {
+            highlightConnections(node);
+          }
+        });
+
+        circle.addEventListener('mouseleave', function() {
+          if (!selectedNode) {
+            clearHighlight();
+          }
+        });
+
+        // Click to lock selection, second click to navigate
+        circle.addEventListener('click', function(e) {
+          e.preventDefault();
+          if (selectedNode === node) {
+            // Second click - navigate
+            window.location.href = node.url || '/';
+          } else {
+            // First click - lock highlight
+            selectedNode = node;
+            highlightConnections(node);
+          }
+        });
+
+        nodeGroup.appendChild(circle);
+        node.element = circle;
+
+        var text = document.createElementNS('http://www.w3.org/2000/svg', 'text');
+        text.setAttribute('class', 'lp-graph-label');
+        text.setAttribute('text-anchor', 'middle');
+        text.setAttribute('font-size', '0.5em');
+        text.setAttribute('pointer-events', 'none');
+        text.style.opacity = '0';
+        text.style.fill = getComputedStyle(document.documentElement).getPropertyValue('--lp-text').trim();
+
+        // Split long titles into multiple lines
+        var title = node.title || 'Home';
+        var maxChars = 18;
+        var lines = [];
+
+        if (title.length <= maxChars) {
+          lines.push(title);
+        } else {
+          // Split into words and create lines
+          var words = title.split(/[\s-]+/);
+          var currentLine = '';
+
+          words.forEach(function(word) {
+            if ((currentLine + ' ' + word).trim().length <= maxChars) {
+              currentLine = (currentLine + ' ' + word).trim();
+            } else {
+              if (currentLine) lines.push(currentLine);
+              currentLine = word;
+            }
+          });
+          if (currentLine) lines.push(currentLine);
+
+          // Limit to 2 lines max
+          if (lines.length > 2) {
+            lines = [lines[0], lines[1].substring(0, maxChars - 3) + '...'];
+          }
+        }
+
+        // Store lines for positioning after simulation
+        node.labelLines = lines;
+
+        labelGroup.appendChild(text);
+        node.label = text;
+      });
+
+      // Click on empty space clears selection
+      svg.addEventListener('click', function(e) {
+        if (e.target === svg) {
+          selectedNode = null;
+          clearHighlight();
+        }
+      });
+
+      function highlightConnections(selected) {
+        var currentAccentColor = getComputedStyle(document.documentElement).getPropertyValue('--lp-accent').trim();
+        nodes.forEach(function(n) {
+          n.element.style.opacity = '0.15';
+          if (n.label) n.label.style.opacity = '0';
+        });
+        links.forEach(function(l) {
+          l.element.style.opacity = '0.05';
+        });
+
+        selected.element.style.opacity = '1';
+        selected.element.setAttribute('r', '8');
+        if (selected.label) selected.label.style.opacity = '1';
+
+        links.forEach(function(link) {
+          if (link.sourceId === selected.id || link.targetId === selected.id) {
+            link.element.style.opacity = '0.8';
+            link.element.setAttribute('stroke', currentAccentColor);
+            link.element.setAttribute('stroke-width', '2.5');
+
+            var connected = link.sourceId === selected.id ? nodeMap[link.targetId] : nodeMap[link.sourceId];
+            if (connected) {
+              connected.element.style.opacity = '1';
+              connected.element.setAttribute('r', '7');
+              if (connected.label) connected.label.style.opacity = '0.9';
+            }
+          }
+        });
+      }
+
+      function clearHighlight() {
+        var currentLinkColor = document.documentElement.getAttribute('data-theme') === 'dark' ? '#444444' : '#d0d0d0';
+        nodes.forEach(function(n) {
+          n.element.style.opacity = '1';
+          n.element.setAttribute('r', n.id === currentSlug ? '8' : '6');
+          if (n.label) n.label.style.opacity = n.id === currentSlug ? '1' : '0';
+        });
+        links.forEach(function(l) {
+          l.element.style.opacity = '0.5';
+          l.element.setAttribute('stroke', currentLinkColor);
+          l.element.setAttribute('stroke-width', '1.5');
+        });
+      }
+
+      // Pass 2: Physics simulation with tag-based clustering and centrality
+      function simulate() {
+        var n = nodes.length;
+        if (n === 0) return;
+
+        var area = width * height;
+        var idealSpacing = Math.sqrt(area / n);
+
+        // Link distance: longer for better spread
+        var linkRestLength = Math.max(120, Math.min(280, idealSpacing * 0.75));
+        var tagRestLength = linkRestLength * 1.1;
+        var clusterRestLength = linkRestLength * 1.3;
+        var collisionRadius = 25;
+
+        // Stronger repulsion for better spread
+        var repulsionStrength = idealSpacing * idealSpacing * 1.2;
+
+        // Much weaker center force - let nodes spread naturally
+        var centerForce = 0.006;
+
+        var iterations = Math.min(350, 120 + n * 6);
+        var padding = 35;
+
+        var alpha = 0.3;
+        var alphaDecay = 0.995;
+
+        for (var k = 0; k < iterations; k++) {
+          // Reset velocities
+          nodes.forEach(function(node) { node.vx = 0; node.vy = 0; });
+
+          // Node-node forces
+          for (var i = 0; i < n; i++) {
+            for (var j = i + 1; j < n; j++) {
+              var a = nodes[i];
+              var b = nodes[j];
+              var dx = b.x - a.x;
+              var dy = b.y - a.y;
+              var dist = Math.sqrt(dx * dx + dy * dy);
+
+              // Prevent division by zero
+              if (dist < 1) {
+                dx = (Math.random() - 0.5) * 2;
+                dy = (Math.random() - 0.5) * 2;
+                dist = 1;
+              }
+
+              var force = 0;
+              var connected = areConnected(a, b);
+              var sharedTags = sharedTagCount(a, b);
+              var clustered = !connected && shareNeighbors(a, b);
+
+              // Centrality weighting: high-degree nodes exert more influence
+              var centralityMult = 1 + (getCentrality(a) + getCentrality(b)) * 0.5;
+
+              if (connected) {
+                // Connected nodes: strong spring attraction (link force = 1.0 in Obsidian)
+                // Higher centrality = stronger pull
+                var displacement = dist - linkRestLength;
+                force = displacement * 0.1 * centralityMult;
+              } else if (sharedTags > 0) {
+                // Nodes with shared tags: attraction based on tag overlap
+                var displacement = dist - tagRestLength;
+                var tagStrength = 0.08 * Math.min(sharedTags, 3); // Cap at 3 shared tags
+                if (displacement > 0) {
+                  force = displacement * tagStrength;
+                } else {
+                  // Still repel if too close
+                  force = -repulsionStrength * 0.2 / (dist * dist);
+                }
+              } else if (clustered) {
+                // Nodes sharing neighbors: weaker attraction
+                var displacement = dist - clusterRestLength;
+                if (displacement > 0) {
+                  force = displacement * 0.04;
+                } else {
+                  force = -repulsionStrength * 0.3 / (dist * dist);
+                }
+              } else {
+                // Unrelated nodes: repulsion with distance falloff
+                force = -repulsionStrength / (dist * dist);
+
+                // Reduced repulsion at large distances (allows clusters)
+                if (dist > idealSpacing * 2) {
+                  force *= 0.25;
+                }
+              }
+
+              // Collision avoidance
+              if (dist < collisionRadius * 2) {
+                force -= (collisionRadius * 2 - dist) * 3;
+              }
+
+              var fx = (force * dx) / dist;
+              var fy = (force * dy) / dist;
+              a.vx += fx;
+              a.vy += fy;
+              b.vx -= fx;
+              b.vy -= fy;
+            }
+          }
+
+          // Center gravity (0.52 in Obsidian = strong pull toward center)
+          var cx = width / 2;
+          var cy = height / 2;
+          nodes.forEach(function(node) {
+            var dx = cx - node.x;
+            var dy = cy - node.y;
+            node.vx += dx * centerForce;
+            node.vy += dy * centerForce;
+          });
+
+          // Apply velocities with damping
+          nodes.forEach(function(node) {
+            // Velocity damping
+            node.vx *= 0.85;
+            node.vy *= 0.85;
+
+            node.x += node.vx * alpha;
+            node.y += node.vy * alpha;
+
+            // Keep within bounds with padding
+            node.x = Math.max(padding, Math.min(width - padding, node.x));
+            node.y = Math.max(padding, Math.min(height - padding, node.y));
+          });
+
+          alpha *= alphaDecay;
+
+          // Early termination if simulation has settled
+          if (alpha < 0.005) break;
+        }
+
+        // Update DOM positions
+        var centerY = height / 2;
+        nodes.forEach(function(node) {
+          node.element.setAttribute('cx', node.x);
+          node.element.setAttribute('cy', node.y);
+          if (node.label && node.labelLines) {
+            // Clear existing tspans
+            while (node.label.firstChild) {
+              node.label.removeChild(node.label.firstChild);
+            }
+
+            // Position label above or below based on node position
+            // Nodes in top half -> label below, nodes in bottom half -> label above
+            var labelBelow = node.y < centerY;
+            var lineHeight = 12;
+            var offset = labelBelow ? 16 : -(8 + (node.labelLines.length - 1) * lineHeight);
+
+            node.label.setAttribute('x', node.x);
+            node.label.setAttribute('y', node.y);
+
+            node.labelLines.forEach(function(line, idx) {
+              var tspan = document.createElementNS('http://www.w3.org/2000/svg', 'tspan');
+              tspan.setAttribute('x', node.x);
+              tspan.setAttribute('dy', idx === 0 ? offset : lineHeight);
+              tspan.textContent = line;
+              node.label.appendChild(tspan);
+            });
+          }
+        });
+
+        links.forEach(function(link) {
+          link.element.setAttribute('x1', link.source.x);
+          link.element.setAttribute('y1', link.source.y);
+          link.element.setAttribute('x2', link.target.x);
+          link.element.setAttribute('y2', link.target.y);
+        });
+
+        // Highlight current node after simulation
+        if (currentSlug) {
+          var current = nodeMap[currentSlug];
+          if (current) {
+            current.element.setAttribute('r', '8');
+            if (current.label) current.label.style.opacity = '1';
+          }
+        }
+      }
+
+      simulate();
+    }
+  })();
+  {{end}}
+  {{if .Search}}
+  // Search functionality
+  (function() {
+    var overlay = document.getElementById('lp-search-overlay');
+    var input = document.getElementById('lp-search-input');
+    var results = document.getElementById('lp-search-results');
+    var toggleBtn = document.querySelector('.lp-search-toggle');
+    var backdrop = overlay.querySelector('.lp-search-backdrop');
+    var searchIndex = null;
+    var selectedIndex = -1;
+
+    function openSearch() {
+      overlay.classList.add('lp-search-overlay--open');
+      overlay.setAttribute('aria-hidden', 'false');
+      document.body.style.overflow = 'hidden';
+      input.value = '';
+      results.innerHTML = '';
+      selectedIndex = -1;
+
+      // Focus input - immediate focus for mobile touch events
+      input.focus();
+      // Backup focus after transition completes
+      setTimeout(function() { input.focus(); }, 200);
+
+      if (!searchIndex) {
+        fetch(LP_BASE_PATH + '/search-index.json')
+          .then(function(r) { return r.json(); })
+          .then(function(data) { searchIndex = data; });
+      }
+    }
+
+    function closeSearch() {
+      overlay.classList.remove('lp-search-overlay--open');
+      overlay.setAttribute('aria-hidden', 'true');
+      document.body.style.overflow = '';
+    }
+
+    function search(query) {
+      if (!searchIndex || !query.trim()) {
+        results.innerHTML = '';
+        selectedIndex = -1;
+        return;
+      }
+
+      var q = query.toLowerCase();
+      var scored = [];
+      searchIndex.forEach(function(item) {
+        var titleLower = item.title.toLowerCase();
+        var contentLower = item.content.toLowerCase();
+        var score = 0;
+
+        // Title matches (highest priority)
+        if (titleLower === q) {
+          score = 100; // Exact title match
+        } else if (titleLower.indexOf(q) === 0) {
+          score = 80; // Title starts with query
+        } else if (titleLower.indexOf(q) !== -1) {
+          score = 60; // Title contains query
+        }
+
+        // Tag matches
+        if (item.tags && item.tags.some(function(t) { return t.toLowerCase().indexOf(q) !== -1; })) {
+          score = Math.max(score, 40);
+        }
+
+        // Content matches (lowest priority)
+        if (contentLower.indexOf(q) !== -1) {
+          score = Math.max(score, 20);
+        }
+
+        if (score > 0) {
+          scored.push({ item: item, score: score });
+        }
+      });
+
+      // Sort by score descending
+      scored.sort(function(a, b) { return b.score - a.score; });
+      var matches = scored.slice(0, 10).map(function(s) { return s.item; });
+
+      if (matches.length === 0) {
+        results.innerHTML = '<div class="lp-search-empty">No results found</div>';
+        selectedIndex = -1;
+        return;
+      }
+
+      results.innerHTML = matches.map(function(item, i) {
+        var snippet = getSnippet(item.content, q);
+        return '<a class="lp-search-result" href="' + item.url + '" data-index="' + i + '">' +
+          '<span class="lp-search-result-title">' + highlightMatch(item.title, q) + '</span>' +
+          (snippet ? '<span class="lp-search-result-snippet">' + highlightMatch(snippet, q) + '</span>' : '') +
+          '</a>';
+      }).join('');
+      selectedIndex = -1;
+    }
+
+    function getSnippet(content, query) {
+      var idx = content.toLowerCase().indexOf(query);
+      if (idx === -1) return '';
+      var start = Math.max(0, idx - 40);
+      var end = Math.min(content.length, idx + query.length + 60);
+      var snippet = content.substring(start, end);
+      if (start > 0) snippet = '...' + snippet;
+      if (end < content.length) snippet = snippet + '...';
+      return snippet;
+    }
+
+    function highlightMatch(text, query) {
+      var regex = new RegExp('(' + query.replace(/[.*+?^${}()|[\]\\]/g, '\\$&') + ')', 'gi');
+      return text.replace(regex, '<mark>$1</mark>');
+    }
+
+    function updateSelection() {
+      var items = results.querySelectorAll('.lp-search-result');
+      items.forEach(function(item, i) {
+        item.classList.toggle('lp-search-result--selected', i === selectedIndex);
+      });
+      if (selectedIndex >= 0 && items[selectedIndex]) {
+        items[selectedIndex].scrollIntoView({ block: 'nearest' });
+      }
+    }
+
+    var closeBtn = overlay.querySelector('.lp-search-close');
+
+    if (toggleBtn) toggleBtn.addEventListener('click', openSearch);
+    backdrop.addEventListener('click', closeSearch);
+    if (closeBtn) closeBtn.addEventListener('click', closeSearch);
+
+    input.addEventListener('input', function() {
+      search(input.value);
+    });
+
+    input.addEventListener('keydown', function(e) {
+      var items = results.querySelectorAll('.lp-search-result');
+      if (e.key === 'ArrowDown') {
+        e.preventDefault();
+        selectedIndex = Math.min(selectedIndex + 1, items.length - 1);
+        updateSelection();
+      } else if (e.key === 'ArrowUp') {
+        e.preventDefault();
+        selectedIndex = Math.max(selectedIndex - 1, -1);
+        updateSelection();
+      } else if (e.key === 'Enter' && selectedIndex >= 0 && items[selectedIndex]) {
+        e.preventDefault();
+        window.location.href = items[selectedIndex].getAttribute('href');
+      }
+    });
+
+    document.addEventListener('keydown', function(e) {
+      if (e.key === 'Escape' && overlay.classList.contains('lp-search-overlay--open')) {
+        closeSearch();
+      }
+      if ((e.metaKey || e.ctrlKey) && e.key === 'k') {
+        e.preventDefault();
+        if (overlay.classList.contains('lp-search-overlay--open')) {
+          closeSearch();
+        } else {
+          openSearch();
+        }
+      }
+    });
+  })();
+  {{end}}
+
+  // Link preview on hover
+  (function() {
+    var previewEl = null;
+    var previewIndex = null;
+    var hideTimeout = null;
+    var currentLink = null;
+
+    function createPreview() {
+      if (previewEl) return;
+      previewEl = document.createElement('div');
+      previewEl.className = 'lp-link-preview';
+      previewEl.innerHTML = '<div class="lp-link-preview-title"></div><div class="lp-link-preview-content"></div>';
+      document.body.appendChild(previewEl);
+
+      previewEl.addEventListener('mouseenter', function() {
+        clearTimeout(hideTimeout);
+      });
+      previewEl.addEventListener('mouseleave', function() {
+        hidePreview();
+      });
+    }
+
+    function showPreview(link, item) {
+      createPreview();
+      clearTimeout(hideTimeout);
+      currentLink = link;
+
+      var title = previewEl.querySelector('.lp-link-preview-title');
+      var content = previewEl.querySelector('.lp-link-preview-content');
+      title.textContent = item.title;
+      content.textContent = item.content.substring(0, 200) + (item.content.length > 200 ? '...' : '');
+
+      var rect = link.getBoundingClientRect();
+      var scrollTop = window.pageYOffset || document.documentElement.scrollTop;
+      var scrollLeft = window.pageXOffset || document.documentElement.scrollLeft;
+
+      previewEl.style.display = 'block';
+      previewEl.style.opacity = '0';
+
+      // Position below link by default
+      var top = rect.bottom + scrollTop + 8;
+      var left = rect.left + scrollLeft;
+
+      // Check if preview would go off-screen bottom
+      var previewHeight = previewEl.offsetHeight;
+      if (rect.bottom + previewHeight + 20 > window.innerHeight) {
+        top = rect.top + scrollTop - previewHeight - 8;
+      }
+
+      // Check if preview would go off-screen right
+      var previewWidth = previewEl.offsetWidth;
+      if (left + previewWidth > window.innerWidth - 20) {
+        left = window.innerWidth - previewWidth - 20;
+      }
+
+      previewEl.style.top = top + 'px';
+      previewEl.style.left = left + 'px';
+      previewEl.style.opacity = '1';
+    }
+
+    function hidePreview() {
+      hideTimeout = setTimeout(function() {
+        if (previewEl) {
+          previewEl.style.display = 'none';
+        }
+        currentLink = null;
+      }, 100);
+    }
+
+    function loadPreviewIndex(callback) {
+      if (previewIndex) {
+        callback(previewIndex);
+        return;
+      }
+      fetch(LP_BASE_PATH + '/search-index.json')
+        .then(function(r) { return r.json(); })
+        .then(function(data) {
+          previewIndex = {};
+          data.forEach(function(item) {
+            previewIndex[item.url] = item;
+          });
+          callback(previewIndex);
+        })
+        .catch(function() {
+          previewIndex = {};
+          callback(previewIndex);
+        });
+    }
+
+    // Attach to all wikilinks, backlinks and query results
+    document.querySelectorAll('.lp-wikilink, .lp-backlink, .lp-query-link').forEach(function(link) {
+      var url = link.getAttribute('href');
+
+      link.addEventListener('mouseenter', function() {
+        loadPreviewIndex(function(index) {
+          var item = index[url];
+          if (item) {
+            showPreview(link, item);
+          }
+        });
+      });
+
+      link.addEventListener('mouseleave', function() {
+        hidePreview();
+      });
+    });
+  })();
+});
+`
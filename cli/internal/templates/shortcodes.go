@@ -0,0 +1,147 @@
+package templates
+
+import (
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shivamx96/leafpress/cli/internal/content"
+)
+
+// builtinShortcodes are the shortcodes every site has. Sites can override
+// them, or add their own, with layouts/shortcodes/<name>.html.
+var builtinShortcodes = map[string]string{
+	"figure": `<figure class="lp-figure{{with .Get "class"}} {{.}}{{end}}">
+  {{- $src := .Get "src" "0"}}
+  <img src="{{.URL $src}}" alt="{{.Get "alt" "caption"}}"{{with .Get "width"}} width="{{.}}"{{end}}{{with .Get "height"}} height="{{.}}"{{end}} loading="lazy" decoding="async">
+  {{- if .Inner}}
+  <figcaption>{{.Inner}}</figcaption>
+  {{- else}}{{with .Get "caption"}}
+  <figcaption>{{.}}</figcaption>
+  {{- end}}{{end}}
+</figure>`,
+
+	"youtube-nocookie": `<div class="lp-video-embed">
+  <iframe src="https://www.youtube-nocookie.com/embed/{{.Get "id" "0"}}{{with .Get "start"}}?start={{.}}{{end}}" title="{{or (.Get "title") "YouTube video"}}" allow="accelerometer; clipboard-write; encrypted-media; gyroscope; picture-in-picture" allowfullscreen loading="lazy"></iframe>
+</div>`,
+
+	"details": `<details class="lp-details"{{if .Flag "open"}} open{{end}}>
+  <summary>{{or (.Get "summary" "0") "Details"}}</summary>
+  <div class="lp-details-content">{{.Inner}}</div>
+</details>`,
+
+	"video": `<video class="lp-video" src="{{.URL (.Get "src" "0")}}"{{with .Get "poster"}} poster="{{$.URL .}}"{{end}} controls preload="metadata"{{if .Flag "autoplay"}} autoplay muted{{end}}{{if .Flag "loop"}} loop{{end}}{{if .Flag "muted"}} muted{{end}} playsinline>
+  {{- .Inner -}}
+</video>`,
+}
+
+// Shortcodes renders the built-in and site shortcodes
+type Shortcodes struct {
+	templates *template.Template
+	basePath  string
+}
+
+// ShortcodeData is the data passed to shortcode templates
+type ShortcodeData struct {
+	Params   map[string]string // Named params, plus positional ones under "0", "1", ...
+	Inner    template.HTML     // Rendered markdown between paired tags ("" for a single tag)
+	Page     *content.Page     // Page the shortcode is on (nil when unknown)
+	BasePath string            // Path portion of baseURL (e.g., "/repo-name")
+}
+
+// Get returns the first of the named or positional params that is set,
+// e.g. {{.Get "src" "0"}} for src="x.png" or a bare x.png
+func (d ShortcodeData) Get(keys ...string) string {
+	for _, key := range keys {
+		if value := d.Params[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Flag reports whether a boolean param is on: set to anything but "false",
+// or given as a bare word ({{< video x.mp4 loop >}})
+func (d ShortcodeData) Flag(name string) bool {
+	if value, ok := d.Params[name]; ok {
+		return value != "false"
+	}
+	for key, value := range d.Params {
+		if value == name && key != "" && key[0] >= '0' && key[0] <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// URL prefixes site-absolute paths with the base path
+func (d ShortcodeData) URL(path string) string {
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") {
+		return d.BasePath + path
+	}
+	return path
+}
+
+// NewShortcodes parses the built-in shortcodes, then any shortcodes/*.html in
+// layoutDirs (lowest priority first), which replace built-ins of the same name
+func NewShortcodes(basePath string, layoutDirs ...string) (*Shortcodes, error) {
+	t := template.New("shortcodes").Funcs(templateFuncs)
+
+	names := make([]string, 0, len(builtinShortcodes))
+	for name := range builtinShortcodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := t.New(name).Parse(builtinShortcodes[name]); err != nil {
+			return nil, fmt.Errorf("built-in shortcode %s: %w", name, err)
+		}
+	}
+
+	for _, dir := range layoutDirs {
+		files, err := filepath.Glob(filepath.Join(dir, "shortcodes", "*.html"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, path := range files {
+			file, err := readLayoutFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if err := overlay(t, file, strings.TrimSuffix(filepath.Base(path), ".html")); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &Shortcodes{templates: t, basePath: basePath}, nil
+}
+
+// HasShortcode reports whether a shortcode is defined
+func (s *Shortcodes) HasShortcode(name string) bool {
+	t := s.templates.Lookup(name)
+	return t != nil && t.Tree != nil
+}
+
+// RenderShortcode renders a shortcode to HTML
+func (s *Shortcodes) RenderShortcode(name string, params map[string]string, inner string, page *content.Page) (string, error) {
+	t := s.templates.Lookup(name)
+	if t == nil {
+		return "", fmt.Errorf("unknown shortcode %s", name)
+	}
+
+	var buf strings.Builder
+	err := t.Execute(&buf, ShortcodeData{
+		Params:   params,
+		Inner:    template.HTML(inner),
+		Page:     page,
+		BasePath: s.basePath,
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}